@@ -0,0 +1,48 @@
+package commander
+
+import (
+	"os"
+	"time"
+)
+
+// NoUsageReportsEnv is the environment variable that keeps the Commander from sending reports to
+// its UsageReporter when it is set to anything.
+const NoUsageReportsEnv = "COMMANDER_NO_USAGE_REPORTS"
+
+// FallbackCommand stands for the command handled by the FallbackHandler of the application in the
+// path of a UsageReport.
+const FallbackCommand = "*"
+
+// UsageReporter is the interface of the analytics that learn which commands of an application are
+// used. ReportUsage is called in its own goroutine, so it may not get to run before the process
+// exits.
+type UsageReporter interface {
+	ReportUsage(report UsageReport)
+}
+
+// UsageReport describes a run of the application without any of the values given to it: neither
+// the arguments nor the flags of the command are part of it.
+type UsageReport struct {
+	// Path is the path of subcommands and command that was run, empty if the run did not get as
+	// far as resolving a command.
+	Path []string
+
+	Duration time.Duration
+	Success  bool
+}
+
+// reportUsage sends the report of the invocation to the UsageReporter of the Commander.
+func (commander Commander) reportUsage(inv *invocation, duration time.Duration, err error) {
+	if commander.UsageReporter == nil || os.Getenv(NoUsageReportsEnv) != "" {
+		return
+	}
+	path := append([]string{}, inv.path...)
+	if inv.fallback {
+		path[len(path)-1] = FallbackCommand
+	}
+	go commander.UsageReporter.ReportUsage(UsageReport{
+		Path:     path,
+		Duration: duration,
+		Success:  err == nil,
+	})
+}
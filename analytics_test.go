@@ -0,0 +1,39 @@
+package commander_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/apourchet/commander"
+	"github.com/stretchr/testify/require"
+)
+
+type chanReporter chan commander.UsageReport
+
+func (reporter chanReporter) ReportUsage(report commander.UsageReport) { reporter <- report }
+
+func TestUsageReporter(t *testing.T) {
+	reporter := make(chanReporter, 1)
+	cmd := commander.New()
+	cmd.UsageOutput = ioutil.Discard
+	cmd.UsageReporter = reporter
+
+	require.NoError(t, cmd.RunCLI(&Application{SubApp: &SubApplication{}}, []string{"subapp", "openv", "secret", "token=1"}))
+	report := <-reporter
+	require.Equal(t, []string{"subapp", "openv"}, report.Path)
+	require.True(t, report.Success)
+
+	require.Error(t, cmd.RunCLI(&Application{}, []string{"opthree"}))
+	report = <-reporter
+	require.Equal(t, []string{"opthree"}, report.Path)
+	require.False(t, report.Success)
+
+	require.NoError(t, cmd.RunCLI(&FallbackApplication{}, []string{"my-bucket"}))
+	require.Equal(t, []string{commander.FallbackCommand}, (<-reporter).Path)
+
+	os.Setenv(commander.NoUsageReportsEnv, "1")
+	defer os.Unsetenv(commander.NoUsageReportsEnv)
+	require.NoError(t, cmd.RunCLI(&Application{}, []string{"opone", "test"}))
+	require.Len(t, reporter, 0)
+}
@@ -0,0 +1,61 @@
+package commander
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// BatchCommentPrefix starts the lines that RunBatch ignores.
+const BatchCommentPrefix = "#"
+
+// LineError is the error of a single line run by RunBatch.
+type LineError struct {
+	Line int
+	Err  error
+}
+
+func (err LineError) Error() string {
+	return fmt.Sprintf("line %d: %v", err.Line, err.Err)
+}
+
+// BatchError is returned by RunBatch when some of the lines failed to run.
+type BatchError struct {
+	Failures []LineError
+}
+
+func (err BatchError) Error() string {
+	messages := make([]string, len(err.Failures))
+	for i, failure := range err.Failures {
+		messages[i] = failure.Error()
+	}
+	return fmt.Sprintf("%d lines failed:\n%s", len(err.Failures), strings.Join(messages, "\n"))
+}
+
+// RunBatch reads invocations of the application from the reader, one per line, and runs each of
+// them with RunCLIString on the same application object. Empty lines and lines starting with
+// BatchCommentPrefix are skipped. Every line is run even if a previous one failed, and the
+// failures are returned together as a BatchError.
+func (commander Commander) RunBatch(app interface{}, r io.Reader) error {
+	failures := []LineError{}
+	scanner := bufio.NewScanner(r)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, BatchCommentPrefix) {
+			continue
+		}
+		if err := commander.RunCLIString(app, line); err != nil {
+			failures = append(failures, LineError{Line: lineno, Err: err})
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return errors.Wrap(err, "failed to read batch")
+	} else if len(failures) > 0 {
+		return BatchError{Failures: failures}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package commander_test
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/apourchet/commander"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunBatch(t *testing.T) {
+	cmd := commander.New()
+	cmd.UsageOutput = ioutil.Discard
+
+	t.Run("shared_state", func(t *testing.T) {
+		app := &Application{SubApp: &SubApplication{}}
+		batch := `# A comment
+opone test
+
+optwo 30
+  subapp opthree
+`
+		err := cmd.RunBatch(app, strings.NewReader(batch))
+		require.NoError(t, err)
+		require.Equal(t, 2, app.count)
+		require.Equal(t, 1, app.SubApp.count)
+	})
+
+	t.Run("quoted", func(t *testing.T) {
+		app := &Application{SubApp: &SubApplication{}}
+		batch := "opvariadic name 'a b' \"c d\"\nsubapp opfour 'test=testing'\nopone 'unterminated\n"
+		err := cmd.RunBatch(app, strings.NewReader(batch))
+		require.Error(t, err)
+		require.Equal(t, 2, app.count)
+		require.Equal(t, 1, app.SubApp.count)
+		require.Len(t, err.(commander.BatchError).Failures, 1)
+		require.Equal(t, 3, err.(commander.BatchError).Failures[0].Line)
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		app := &Application{}
+		batch := "opone test\nopthree\nunknown\nopone test\n"
+		err := cmd.RunBatch(app, strings.NewReader(batch))
+		require.Error(t, err)
+		require.Equal(t, 2, app.count)
+
+		batchErr, ok := err.(commander.BatchError)
+		require.True(t, ok)
+		require.Len(t, batchErr.Failures, 2)
+		require.Equal(t, 2, batchErr.Failures[0].Line)
+		require.Equal(t, errTest, batchErr.Failures[0].Err)
+		require.Equal(t, 3, batchErr.Failures[1].Line)
+		require.Contains(t, err.Error(), "line 2: ERROR")
+	})
+}
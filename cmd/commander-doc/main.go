@@ -0,0 +1,200 @@
+// Command commander-doc generates the command descriptions and argument names of commander
+// applications from the Go doc comments on their command methods, so that the help text of a CLI
+// lives next to the code. It is meant to be used with go:generate:
+//
+//	//go:generate commander-doc -type Manager,HTTPCLI
+//
+// For each type, the generated file implements commander.CommandDescriptionProvider using the
+// first sentence of the doc comment of each exported method, and commander.ArgumentNamesProvider
+// using the names of the method parameters.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultOutput is the name of the generated file, relative to the package directory.
+const DefaultOutput = "commander_doc.go"
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("commander-doc: ")
+
+	types := flag.String("type", "", "comma-separated list of the application types to document")
+	output := flag.String("output", DefaultOutput, "name of the generated file")
+	args := flag.Bool("args", true, "also generate the argument names of the commands")
+	flag.Parse()
+
+	if *types == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+
+	content, err := generate(dir, *output, strings.Split(*types, ","), *args)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, *output), content, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// command is a documented command method of an application type.
+type command struct {
+	name        string
+	description string
+	arguments   []string
+}
+
+// generate parses the package in dir and returns the source of the generated file.
+func generate(dir string, output string, types []string, withArgs bool) ([]byte, error) {
+	fset := token.NewFileSet()
+	filter := func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go") && info.Name() != output
+	}
+	pkgs, err := parser.ParseDir(fset, dir, filter, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	} else if len(pkgs) != 1 {
+		return nil, fmt.Errorf("expected exactly one package in %v, found %d", dir, len(pkgs))
+	}
+
+	var pkg *ast.Package
+	for _, p := range pkgs {
+		pkg = p
+	}
+
+	commands := map[string][]command{}
+	for _, typ := range types {
+		commands[typ] = nil
+	}
+	for _, file := range pkg.Files {
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv == nil || !fn.Name.IsExported() {
+				continue
+			}
+			typ := receiverName(fn.Recv.List[0].Type)
+			if _, found := commands[typ]; !found {
+				continue
+			}
+			commands[typ] = append(commands[typ], command{
+				name:        fn.Name.Name,
+				description: doc.Synopsis(fn.Doc.Text()),
+				arguments:   argumentNames(fn.Type.Params),
+			})
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by commander-doc; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %v\n\n", pkg.Name)
+	fmt.Fprintf(&buf, "import \"strings\"\n\n")
+	fmt.Fprintf(&buf, "var commanderDocReplacer = strings.NewReplacer(\"-\", \"\", \"_\", \"\")\n")
+
+	for _, typ := range types {
+		cmds := commands[typ]
+		if cmds == nil {
+			return nil, fmt.Errorf("no exported methods found for type %v", typ)
+		}
+		sort.Slice(cmds, func(i, j int) bool { return cmds[i].name < cmds[j].name })
+
+		fmt.Fprintf(&buf, "\n// GetCommandDescription implements commander.CommandDescriptionProvider.\n")
+		fmt.Fprintf(&buf, "func (%v) GetCommandDescription(cmd string) string {\n", typ)
+		fmt.Fprintf(&buf, "switch strings.ToLower(commanderDocReplacer.Replace(cmd)) {\n")
+		for _, cmd := range cmds {
+			if cmd.description == "" {
+				continue
+			}
+			fmt.Fprintf(&buf, "case %q:\nreturn %q\n", strings.ToLower(cmd.name), cmd.description)
+		}
+		fmt.Fprintf(&buf, "}\nreturn \"\"\n}\n")
+
+		if !withArgs {
+			continue
+		}
+		fmt.Fprintf(&buf, "\n// GetArgumentNames implements commander.ArgumentNamesProvider.\n")
+		fmt.Fprintf(&buf, "func (%v) GetArgumentNames(cmd string) []string {\n", typ)
+		fmt.Fprintf(&buf, "switch strings.ToLower(commanderDocReplacer.Replace(cmd)) {\n")
+		for _, cmd := range cmds {
+			if len(cmd.arguments) == 0 {
+				continue
+			}
+			fmt.Fprintf(&buf, "case %q:\nreturn %#v\n", strings.ToLower(cmd.name), cmd.arguments)
+		}
+		fmt.Fprintf(&buf, "}\nreturn nil\n}\n")
+	}
+	return format.Source(buf.Bytes())
+}
+
+// receiverName returns the name of the type of a method receiver, be it a pointer or not.
+func receiverName(expr ast.Expr) string {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	if ident, ok := expr.(*ast.Ident); ok {
+		return ident.Name
+	}
+	return ""
+}
+
+// argumentNames returns the names of the positional parameters of a command method, leaving out
+// the leading context.Context, io.Reader and io.Writer that commander injects and the trailing
+// slice or map that collects extra arguments, since none of them can be given by name on the
+// command line.
+func argumentNames(params *ast.FieldList) []string {
+	fields := params.List
+	for len(fields) > 0 && isInjectedType(fields[0].Type) {
+		fields = fields[1:]
+	}
+
+	names := []string{}
+	for i, field := range fields {
+		if i == len(fields)-1 && isTrailingType(field.Type) {
+			break
+		}
+		for _, name := range field.Names {
+			names = append(names, name.Name)
+		}
+	}
+	return names
+}
+
+func isInjectedType(expr ast.Expr) bool {
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	name := pkg.Name + "." + sel.Sel.Name
+	return name == "context.Context" || name == "io.Reader" || name == "io.Writer"
+}
+
+func isTrailingType(expr ast.Expr) bool {
+	switch expr.(type) {
+	case *ast.ArrayType, *ast.MapType, *ast.Ellipsis:
+		return true
+	}
+	return false
+}
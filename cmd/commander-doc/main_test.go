@@ -0,0 +1,75 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const source = `package app
+
+import (
+	"context"
+	"io"
+)
+
+type App struct{}
+
+// Read prints the contents of a file. Other files can be given too.
+func (app *App) Read(file string, rest []string) error { return nil }
+
+// Copy copies a file.
+func (app App) Copy(ctx context.Context, out io.Writer, from, to string) error { return nil }
+
+func (app App) Undocumented() {}
+
+func (app App) unexported() {}
+`
+
+const expected = `// Code generated by commander-doc; DO NOT EDIT.
+
+package app
+
+import "strings"
+
+var commanderDocReplacer = strings.NewReplacer("-", "", "_", "")
+
+// GetCommandDescription implements commander.CommandDescriptionProvider.
+func (App) GetCommandDescription(cmd string) string {
+	switch strings.ToLower(commanderDocReplacer.Replace(cmd)) {
+	case "copy":
+		return "Copy copies a file."
+	case "read":
+		return "Read prints the contents of a file."
+	}
+	return ""
+}
+
+// GetArgumentNames implements commander.ArgumentNamesProvider.
+func (App) GetArgumentNames(cmd string) []string {
+	switch strings.ToLower(commanderDocReplacer.Replace(cmd)) {
+	case "copy":
+		return []string{"from", "to"}
+	case "read":
+		return []string{"file"}
+	}
+	return nil
+}
+`
+
+func TestGenerate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "commander-doc")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "app.go"), []byte(source), 0644))
+
+	content, err := generate(dir, DefaultOutput, []string{"App"}, true)
+	require.NoError(t, err)
+	require.Equal(t, expected, string(content))
+
+	_, err = generate(dir, DefaultOutput, []string{"Missing"}, true)
+	require.Error(t, err)
+}
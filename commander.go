@@ -1,13 +1,16 @@
 package commander
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"os"
 	"reflect"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/apourchet/commander/utils"
 	"github.com/pkg/errors"
@@ -37,6 +40,39 @@ const (
 	// FlagDirective indicates that this field should be populated using the command
 	// line flags
 	FlagDirective = "flag"
+
+	// EnvDirective indicates that this field should be populated from the environment variable
+	// given, without being exposed as a flag. The directive is env=<VAR>[,<usage>], for settings
+	// that must not be passed on the command line.
+	EnvDirective = "env"
+
+	// ArgDirective indicates that the field of the arguments struct of a typed command should be
+	// populated from a positional argument. Positional arguments are assigned in field order, and a
+	// slice field in last position collects the remaining ones. The directive is arg=<name>,
+	// optionally followed by ,placeholder=<NAME> to show the argument as NAME in the synopsis.
+	ArgDirective = "arg"
+
+	// PluginsDirective indicates that the plugins registered through RegisterPlugin should be
+	// mounted as subcommands of the struct that holds this field.
+	PluginsDirective = "plugins"
+
+	// SubcommandsDirective indicates that the field is a map whose keys are the names of
+	// subcommands, and whose values are the applications of those subcommands. This lets the set of
+	// subcommands be decided at runtime.
+	SubcommandsDirective = "subcommands"
+
+	// InjectDirective indicates that the field should be set to the value provided to the Commander
+	// under the name given, through Commander.Provide, before the command runs.
+	InjectDirective = "inject"
+
+	// LockDirective makes the commands of the application take the lock file at the path given
+	// before they run, so that only one invocation of the application runs at a time.
+	LockDirective = "lock"
+
+	// ColumnDirective gives the header of the column of a field when a slice of structs is written
+	// with WriteTable, or returned by a command. A field tagged with "column=-" is left out of the
+	// table.
+	ColumnDirective = "column"
 )
 
 // NamedCLI is the interface that the application should implement to change the default displayed
@@ -51,52 +87,462 @@ type PostFlagParseHook interface {
 	PostFlagParse() error
 }
 
+// PostFlagParseContextHook is like PostFlagParseHook, for applications that need the context given
+// to RunCLIContext.
+type PostFlagParseContextHook interface {
+	PostFlagParseContext(ctx context.Context) error
+}
+
+// PreDispatchHook is the interface that the root application should implement to be called once
+// the command to run has been resolved, with its full path and its arguments, before the flags of
+// the command are set up and it runs. Returning an error stops the command from running, which
+// suits authentication checks and policies that depend on the command.
+type PreDispatchHook interface {
+	PreDispatch(path []string, args []string) error
+}
+
 // CommandDescriptionProvider is the interface that the application should implement to show the
 // description of its subcommands when the Usage of the app is printed.
 type CommandDescriptionProvider interface {
 	GetCommandDescription(cmd string) string
 }
 
+// LongDescriptionProvider is the interface that the application should implement to describe its
+// commands at length. The description of a command is shown below the first line of its usage,
+// wrapped, with its paragraphs separated by blank lines. Lines that start with a space are kept as
+// they are, for examples.
+type LongDescriptionProvider interface {
+	LongDescription(cmd string) string
+}
+
+// ArgumentNamesProvider is the interface that the application should implement to name the
+// parameters of its commands, in order. Go does not keep the names of method parameters, so this
+// is how named arguments are resolved.
+type ArgumentNamesProvider interface {
+	GetArgumentNames(cmd string) []string
+}
+
+// ArgumentCountProvider is the interface that the application should implement to bound the
+// number of arguments of its commands, typically of those whose last parameter collects the extra
+// arguments. A negative max leaves the number of arguments unbounded.
+type ArgumentCountProvider interface {
+	GetArgumentCount(cmd string) (min int, max int)
+}
+
+// FallbackHandler is the interface that the application should implement to handle the commands
+// that match none of its methods and subcommands, like commands named after resources that only
+// exist at runtime. CommanderFallback receives the unknown command and the arguments after it. It
+// runs like a command would, after the hooks and the validators, and while holding the lock.
+type FallbackHandler interface {
+	CommanderFallback(cmd string, args []string) error
+}
+
+// HelpTopicsProvider is the interface that the application should implement to ship documentation
+// that is not attached to a command, like the environment it reads or the format of its files.
+// Topics are listed in the usage of the application, keyed by name, and "app help <topic>" prints
+// their text.
+type HelpTopicsProvider interface {
+	GetHelpTopics() map[string]string
+}
+
+// SubcommandsProvider is the interface that the application should implement to compute its
+// subcommands at runtime, keyed by name. They are looked up after the subcommands of its tags.
+type SubcommandsProvider interface {
+	Subcommands() map[string]interface{}
+}
+
+// CommandPathReceiver is the interface that the application and its subcommands can implement to
+// learn the path of commands under which they are invoked, like ["manage", "copy"]. The path is
+// set before the PostFlagParse hooks run, and ends with the command when the application is the
+// one that executes it.
+type CommandPathReceiver interface {
+	SetCommandPath(path []string)
+}
+
+// AllowedCommandsProvider is the interface that the application should implement to restrict the
+// methods that can be called from the command line. By default every exported method of the
+// application is a command; when this interface is implemented only the commands returned are,
+// including DefaultCommand if the application has one.
+type AllowedCommandsProvider interface {
+	GetAllowedCommands() []string
+}
+
+// CommandNamesProvider is the interface that the application should implement to give commands
+// names that differ from their methods. The map goes from method names to command names, like
+// "ListAll" to "ls"; a renamed method can only be called by its command name.
+type CommandNamesProvider interface {
+	CommandNames() map[string]string
+}
+
+// Validator is the interface that the application, its flagstructs and the elements of its flag
+// slices can implement to check their flags once they are parsed. Validate is called on all of
+// them before the command runs, and the command does not run if any of them fails.
+type Validator interface {
+	Validate() error
+}
+
 // Commander is the struct that CLI applications will interact with
 // to run their code.
 type Commander struct {
 	UsageOutput       io.Writer
 	FlagErrorHandling flag.ErrorHandling
+
+	// AllowSharedFlags lets several fields bind the same flag name instead of failing with a
+	// duplicate binding error. Setting that flag will then set all of those fields.
+	AllowSharedFlags bool
+
+	// ShadowFlags lets a field of a nested flagstruct bind a flag name that a struct holding it
+	// already binds, instead of failing with a duplicate binding error. The most deeply nested field
+	// gets the flag and the others are left alone.
+	ShadowFlags bool
+
+	// WarnFlagAliases prints a deprecation warning to the UsageOutput when a flag is set through one
+	// of the old names given by the alias options of its directive.
+	WarnFlagAliases bool
+
+	// DeclarationOrder lists the flags and the subcommands in the usage in the order of the fields
+	// that declare them rather than by name. Either way, the flags and subcommands whose directive
+	// has an order=<n> option are listed first, by increasing order.
+	DeclarationOrder bool
+
+	// AbbreviatedFlags makes RunCLI accept unambiguous prefixes of the names of flags, like --store
+	// for --store-location. A prefix that several flags start with fails with an error naming them.
+	AbbreviatedFlags bool
+
+	// WarnIrrelevantFlags makes RunCLI ignore the flags given to a command that belong to the
+	// flagstructs of other commands, with a warning on the UsageOutput. Those flags fail the parsing
+	// otherwise, like any flag that is not defined.
+	WarnIrrelevantFlags bool
+
+	// TimeoutFlag adds a top-level --timeout flag to the application, which overrides the timeout
+	// directives of its subcommands. The flag is not added if the application already defines one.
+	TimeoutFlag bool
+
+	// Trace receives a trace of how RunCLI resolves the command to run: the flags defined and
+	// parsed at each level, the subcommands matched and the commands considered. New sets it to
+	// os.Stderr when the DebugEnv environment variable is set.
+	Trace io.Writer
+
+	// OnCommandStart is called right before a command is executed, with the path of commands that
+	// led to it and the arguments it will receive.
+	OnCommandStart func(path []string, args []string)
+
+	// OnCommandEnd is called once a command has been executed, with the time it took and the error
+	// it returned if any.
+	OnCommandEnd func(path []string, args []string, duration time.Duration, err error)
+
+	// OnFlagSetBuilt is called with each flagset that RunCLI builds, before the arguments are parsed
+	// into it, and OnParsed once they are. The level is 0 for the flags of the application, 1 for
+	// those of its subcommand and so on, the flags of the command itself being one level below the
+	// application that implements it.
+	OnFlagSetBuilt func(level int, flagset *FlagSet)
+	OnParsed       func(level int, flagset *FlagSet)
+
+	// PreprocessArgs rewrites the arguments given to RunCLI before anything else looks at them,
+	// which is the place to expand aliases, inject arguments from the environment or translate a
+	// legacy syntax. It is given a copy of the arguments, and RunCLI goes on with the ones returned.
+	PreprocessArgs func(arguments []string) []string
+
+	// History records every invocation of the application when it is set, and adds a history
+	// command that lists those invocations and re-runs them.
+	History *History
+
+	// ExternalPlugins makes the Commander look for an executable named <app>-<cmd> on the PATH
+	// when cmd is not a command of the application, and run it with the remaining arguments. The
+	// flags given so far are passed to it through the PluginFlagsEnv environment variable.
+	ExternalPlugins bool
+
+	// Stdin is the input of the application, and Stdout its output. Commands that take an
+	// io.Reader or an io.Writer as their first parameters receive them.
+	Stdin  io.Reader
+	Stdout io.Writer
+
+	// StdinArgument makes the Commander replace a positional argument of StdinArgumentName with
+	// the contents of Stdin, so that commands can be piped into.
+	StdinArgument bool
+
+	// NamedArguments lets the arguments of a command be given as name=value pairs in any order,
+	// using the names from the ArgumentNamesProvider of the application. Arguments that are not
+	// all named this way are used in positional order.
+	NamedArguments bool
+
+	// StrictOrdering makes RunCLI reject the flags that are given after the first argument of the
+	// command, which would otherwise be passed to the command as arguments.
+	StrictOrdering bool
+
+	// providers holds the values to inject into the fields with an InjectDirective, by name.
+	providers map[string]interface{}
+
+	// outputFormat is the format that the values streamed by the commands are written in, as given
+	// to the --output flag of the StandardFlags.
+	outputFormat string
+
+	// noColor disables the colors of FormatError, as given by the --no-color flag of the
+	// StandardFlags.
+	noColor bool
+
+	// keepFlagValues makes the flagsets of the Commander leave the fields of the applications as
+	// they are, without setting their default values.
+	keepFlagValues bool
+
+	// StrictTags makes RunCLI check the tags of the application with Validate before running it,
+	// and fail if any of them is invalid.
+	StrictTags bool
+
+	// LockFile is the path of the lock file that commands take before they run, so that only one
+	// invocation of the application runs at a time. It overrides the LockDirective of the
+	// application.
+	LockFile string
+
+	// SlashFlags makes RunCLI accept the Windows-style "/flag value" and "/flag:value" syntaxes for
+	// the flags that are defined, along with "/?" for help. Other arguments that start with a slash,
+	// like paths, are left as they are.
+	SlashFlags bool
+
+	// Doctor adds a doctor command to the application, which checks how the application is wired
+	// with RunDoctor.
+	Doctor bool
+
+	// Find adds a find command to the application, which prints the full paths of the commands and
+	// subcommands of the whole tree whose name or description contains a keyword, with FindCommand.
+	Find bool
+
+	// UsageReporter receives a UsageReport after each run of the application, unless the
+	// NoUsageReportsEnv environment variable is set.
+	UsageReporter UsageReporter
+
+	// ColorErrors colors the errors rendered by FormatError, unless the NoColorEnv environment
+	// variable is set.
+	ColorErrors bool
+
+	// LogFileFlag adds a top-level --log-file flag to the application. When it is given, everything
+	// the Commander writes to its UsageOutput and Trace is copied to that file, one timestamped line
+	// at a time, along with the arguments of the invocation and the error it ended with. The values
+	// of the flags with the secret option, or listed by the History, are redacted from the arguments.
+	LogFileFlag bool
+
+	// LogCommandOutput also copies to the file of the --log-file flag what the commands write to
+	// the Stdout of the Commander.
+	LogCommandOutput bool
+
+	// PrintConfigFlag adds a top-level --print-config flag to the application. When it is given,
+	// the command is not run: the Commander writes to its Stdout the value that every flag of the
+	// invocation resolved to and whether it came from the command line, the environment or the
+	// default, redacting the values of the flags with the secret option.
+	PrintConfigFlag bool
+
+	// TableCellWidth is the maximum width of the cells of the tables that the Commander writes for
+	// the slices of structs returned by the commands. Longer cells are truncated. A width of 0 or
+	// less leaves them whole.
+	TableCellWidth int
 }
 
+// StdinArgumentName is the positional argument that stands for the contents of the Stdin of the
+// Commander when StdinArgument is set.
+const StdinArgumentName = "-"
+
+// TimeoutFlagName is the name of the flag added by the Commander when TimeoutFlag is set.
+const TimeoutFlagName = "timeout"
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	readerType  = reflect.TypeOf((*io.Reader)(nil)).Elem()
+	writerType  = reflect.TypeOf((*io.Writer)(nil)).Elem()
+)
+
 // New creates a new instance of the Commander.
 func New() Commander {
-	return Commander{
+	commander := Commander{
 		UsageOutput:       os.Stdout,
 		FlagErrorHandling: flag.ContinueOnError,
+		Stdin:             os.Stdin,
+		Stdout:            os.Stdout,
 	}
+	if debugEnabled() {
+		commander.Trace = os.Stderr
+	}
+	return commander
 }
 
 // RunCLI runs an application given with the command line arguments specified.
 func (commander Commander) RunCLI(app interface{}, arguments []string) error {
+	return commander.RunCLIContext(context.Background(), app, arguments)
+}
+
+// RunCLIString runs an application like RunCLI, with the arguments of a command line given as a
+// single string, like "subapp opfour '{\"a\":1}'". The line is split into arguments like a POSIX
+// shell would with ShellSplit, without expanding variables or globs.
+func (commander Commander) RunCLIString(app interface{}, line string) error {
+	arguments, err := ShellSplit(line)
+	if err != nil {
+		return errors.Wrap(err, "failed to split command line")
+	}
+	return commander.RunCLI(app, arguments)
+}
+
+// RunCLIContext runs an application like RunCLI, passing the context to the PostFlagParseContext
+// hooks and to the commands that take a context.Context as their first parameter. The dispatch
+// stops with the error of the context if it is done before the command is executed.
+func (commander Commander) RunCLIContext(ctx context.Context, app interface{}, arguments []string) error {
+	return commander.run(ctx, app, arguments, &invocation{})
+}
+
+// run runs the application and records the invocation in the history of the Commander.
+func (commander Commander) run(ctx context.Context, app interface{}, arguments []string, inv *invocation) (err error) {
+	defer func(start time.Time) {
+		commander.reportUsage(inv, time.Since(start), err)
+	}(time.Now())
+
+	if commander.PreprocessArgs != nil {
+		arguments = commander.PreprocessArgs(append([]string{}, arguments...))
+		commander.tracef("arguments preprocessed into %v", arguments)
+	}
+
+	if commander.StrictTags {
+		if err := Validate(app); err != nil {
+			return err
+		}
+	}
+
+	if name, ok := flagHelpRequest(app, arguments); ok {
+		return commander.PrintFlagHelp(app, name)
+	} else if text, ok := helpTopicRequest(app, arguments); ok {
+		fmt.Fprintln(commander.UsageOutput, strings.TrimRight(text, "\n"))
+		return nil
+	}
+
+	if commander.Doctor && isDoctorCommand(app, arguments) {
+		return commander.RunDoctor(commander.Stdout, app)
+	} else if commander.Find && isFindCommand(app, arguments) {
+		return commander.runFind(app, arguments[1])
+	}
+
+	if commander.History == nil {
+		return commander.runCLI(ctx, app, arguments, inv)
+	} else if isHistoryCommand(app, arguments) {
+		return commander.runHistory(ctx, app, arguments[1:])
+	}
+
+	err = commander.runCLI(ctx, app, arguments, inv)
+	entry := HistoryEntry{Time: time.Now(), Path: inv.path, Status: ExitCode(err)}
+	entry.Arguments, entry.Redacted = commander.redactedFlags(app).apply(arguments)
+	if recordErr := commander.History.Record(entry); recordErr != nil && err == nil {
+		return errors.Wrap(recordErr, "failed to record invocation")
+	}
+	return err
+}
+
+// invocation holds what RunCLI learns about the command that it runs.
+type invocation struct {
+	path      []string
+	command   string
+	flags     []FlagReport
+	arguments []interface{}
+	appErr    error
+
+	// fallback is true when the command was handled by the FallbackHandler of the application.
+	fallback bool
+}
+
+// recordFlags records the flags of the flagset that were set on the command line or by their
+// environment variable, at the level of the command path given, along with where they were set.
+func (inv *invocation) recordFlags(level []string, flagset *FlagSet) {
+	for _, info := range flagset.Targets() {
+		if info.Changed {
+			inv.flags = append(inv.flags, FlagReport{
+				Name:   info.Name,
+				Value:  info.Value,
+				Level:  append([]string{}, level...),
+				Source: info.Source,
+			})
+		}
+	}
+}
+
+func (commander Commander) runCLI(ctx context.Context, app interface{}, arguments []string, inv *invocation) (runErr error) {
+	invocationArguments := arguments
 	cumulativeCommands := []string{}
+	ancestors := []interface{}{}
+	parsed := []interface{}{}
 	originalApp := app
 	appname := getCLIName(originalApp, cumulativeCommands...)
+	var timeout, timeoutOverride time.Duration
+	var logFilePath string
+	var printConfig bool
+	resolved := []*FlagSet{}
+	parentFlags, changedFlags := map[string]string{}, map[string]string{}
 	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := commander.inject(app); err != nil {
+			return err
+		}
+
 		// Get the flagset from the tags of the app struct
 		flagset, err := commander.GetFlagSet(app, appname)
 		if err != nil {
 			return errors.WithStack(err)
 		}
 
+		if commander.TimeoutFlag && len(cumulativeCommands) == 0 && flagset.Lookup(TimeoutFlagName) == nil {
+			flagset.DurationVar(&timeoutOverride, TimeoutFlagName, 0, "Maximum duration of the command")
+		}
+		if commander.LogFileFlag && len(cumulativeCommands) == 0 && flagset.Lookup(LogFileFlagName) == nil {
+			flagset.StringVar(&logFilePath, LogFileFlagName, "", "File to copy the output of the application to")
+		}
+		if commander.PrintConfigFlag && len(cumulativeCommands) == 0 && flagset.Lookup(PrintConfigFlagName) == nil {
+			flagset.BoolVar(&printConfig, PrintConfigFlagName, false, "Print the resolved value of every flag and its source instead of running the command")
+		}
+		if commander.OnFlagSetBuilt != nil {
+			commander.OnFlagSetBuilt(len(ancestors), flagset)
+		}
+
 		// Parse the arguments into that flagset
-		if err := flagset.Parse(arguments); err != nil {
+		if err := commander.parseFlags(app, flagset, arguments); err != nil {
 			return errors.WithStack(err)
 		}
+		if logFilePath != "" && len(cumulativeCommands) == 0 {
+			redacted, _ := commander.redactedFlags(originalApp).apply(invocationArguments)
+			log, err := openLogFile(logFilePath, redacted)
+			if err != nil {
+				return err
+			}
+			defer func() { log.close(runErr) }()
+			commander = commander.withLogFile(log)
+		}
+		commander.traceFlags(appname, flagset)
+		if commander.OnParsed != nil {
+			commander.OnParsed(len(ancestors), flagset)
+		}
+		for name, value := range flagset.values() {
+			parentFlags[name] = value
+		}
+		for _, info := range flagset.Targets() {
+			if info.Changed {
+				changedFlags[info.Name] = info.Value
+			}
+		}
+		inv.recordFlags(cumulativeCommands, flagset)
+		parsed = append(parsed, flagset.structs...)
+		resolved = append(resolved, flagset)
 
 		if arguments = flagset.Args(); len(arguments) > 0 {
-			if subapp, err := subCommand(app, arguments[0]); err != nil {
+			if subapp, directive, err := subCommand(app, arguments[0]); err != nil {
 				return errors.Wrapf(err, "failed to search for subcommand %v", arguments[0])
 			} else if subapp != nil {
-				if err = executeHook(app); err != nil {
+				setCommandPath(app, cumulativeCommands)
+				if err = executeHook(ctx, app); err != nil {
 					return errors.WithStack(err)
 				}
-				cumulativeCommands = append(cumulativeCommands, arguments[0])
+				if directive.timeout != 0 {
+					timeout = directive.timeout
+				}
+				commander.tracef("%v: subcommand %v matched, descending into %v", appname, arguments[0], typeName(subapp))
+				fmt.Fprint(commander.UsageOutput, directive.warning(arguments[0]))
+				cumulativeCommands = append(cumulativeCommands, directive.cmd)
+				ancestors = append(ancestors, app)
 				app = subapp
 				arguments = arguments[1:]
 				appname = getCLIName(originalApp, cumulativeCommands...)
@@ -104,53 +550,158 @@ func (commander Commander) RunCLI(app interface{}, arguments []string) error {
 			}
 		}
 
+		commandPath := append([]string{}, cumulativeCommands...)
 		commands := getPossibleCommands(arguments, cumulativeCommands)
 		if len(arguments) > 0 {
 			cumulativeCommands = append(cumulativeCommands, arguments[0])
 		}
 
 		cmd, err := findCommand(app, commands)
+		commander.tracef("%v: command candidates %v, chose the first that %v implements: %q", appname, commands, typeName(app), cmd)
 		if err != nil {
 			return err
-		} else if cmd == "" {
+		} else if cmd == "" && commander.ExternalPlugins && len(arguments) > 0 {
+			if found, err := commander.runExternalPlugin(appname, arguments[0], arguments[1:], changedFlags); found {
+				return err
+			}
+		}
+
+		if timeoutOverride != 0 {
+			timeout = timeoutOverride
+		}
+
+		// The unknown command goes to the fallback handler of the application along with the rest
+		// of the arguments, which are not parsed as flags.
+		if _, ok := app.(FallbackHandler); ok && cmd == "" && len(arguments) > 0 {
+			commander.tracef("%v: no command matched, falling back on %v", appname, typeName(app))
+			commandPath = append(commandPath, arguments[0])
+			inv.path, inv.command, inv.fallback = commandPath, arguments[0], true
+			if err := preDispatch(originalApp, commandPath, arguments[1:]); err != nil {
+				return err
+			} else if printConfig {
+				return writeConfig(commander.Stdout, resolved)
+			}
+			return commander.dispatch(ctx, app, ancestors, appname, commandPath, arguments[0], arguments[1:], parsed, timeout, inv)
+		}
+
+		if cmd == "" {
 			commander.PrintUsage(app, appname)
-			return fmt.Errorf("failed to find possible method: %v", commands)
+			return commandNotFoundError(app, appname, arguments, commands)
 		} else if len(arguments) > 0 && cmd == arguments[0] {
 			if len(cumulativeCommands) < 2 || cumulativeCommands[len(cumulativeCommands)-2] != arguments[0] {
+				commandPath = append(commandPath, arguments[0])
 				arguments = arguments[1:]
 			}
 		}
 
-		// Setup the new flags with the deeper flagstruct of this command.
-		flagset, err = commander.GetFlagSetWithCommand(app, appname, cmd)
+		if err := preDispatch(originalApp, commandPath, arguments); err != nil {
+			return err
+		}
+
+		// Setup the new flags with the deeper flagstruct of this command, and with the flagstructs
+		// that the ancestors of the application bind to it through its command path.
+		scoped := map[string]interface{}{cmd: app}
+		for i, ancestor := range ancestors {
+			scoped[strings.Join(append(commandPath[i:len(ancestors):len(ancestors)], cmd), " ")] = ancestor
+		}
+		flagset, err = commander.commandFlagSet(scoped, appname, cmd)
 		if err != nil {
 			return fmt.Errorf("failed to setup flags: %v", err)
 		}
+		if commander.OnFlagSetBuilt != nil {
+			commander.OnFlagSetBuilt(len(ancestors)+1, flagset)
+		}
 
 		// Reparse flags to populate some of the flags that the default package might have missed
-		if err := flagset.Parse(arguments); err != nil {
+		if err := commander.parseFlags(app, flagset, arguments); err != nil {
 			return errors.WithStack(err)
 		}
+		commander.traceFlags(flagset.Name(), flagset)
+		if commander.OnParsed != nil {
+			commander.OnParsed(len(ancestors)+1, flagset)
+		}
+		terminated := len(arguments) > len(flagset.Args()) && arguments[len(arguments)-len(flagset.Args())-1] == "--"
 		arguments = flagset.Args()
+		inv.recordFlags(commandPath, flagset)
+		parsed = append(parsed, flagset.structs...)
+		if printConfig {
+			return writeConfig(commander.Stdout, append(resolved, flagset))
+		}
 
-		err = executeCommand(app, cmd, arguments, flagset.FlagSet)
-		if err != nil && !isApplicationError(err) {
-			commander.PrintUsageWithCommand(app, appname, cmd)
-			return fmt.Errorf("failed to run application: %v", err)
-		} else if err != nil {
-			inner := err.(applicationError)
-			return inner.error
+		if commander.StrictOrdering && !terminated {
+			if err := checkArgumentOrder(cmd, arguments, parentFlags, flagset.values()); err != nil {
+				return err
+			}
 		}
-		return nil
+
+		return commander.dispatch(ctx, app, ancestors, appname, commandPath, cmd, arguments, parsed, timeout, inv)
+	}
+}
+
+// dispatch runs the command of the application once the flags of every level of its command path
+// are set in the structs given: it validates them, applies the StandardFlags, resolves the special
+// arguments and then executes the command between the OnCommandStart and OnCommandEnd hooks, while
+// holding the lock of the application.
+func (commander Commander) dispatch(ctx context.Context, app interface{}, ancestors []interface{}, appname string, commandPath []string, cmd string, arguments []string, parsed []interface{}, timeout time.Duration, inv *invocation) error {
+	if err := validateStructs(parsed); err != nil {
+		return err
+	}
+	commander = commander.withStandardFlags(parsed)
+
+	var err error
+	if commander.NamedArguments {
+		if arguments, err = resolveNamedArguments(app, cmd, arguments); err != nil {
+			return err
+		}
+	}
+
+	if commander.StdinArgument {
+		if arguments, err = replaceStdinArgument(arguments, commander.Stdin); err != nil {
+			return err
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	commander.tracef("%v: executing command %v with arguments %q", appname, cmd, arguments)
+	inv.path, inv.command = commandPath, cmd
+	setCommandPath(app, commandPath)
+	if commander.OnCommandStart != nil {
+		commander.OnCommandStart(commandPath, arguments)
+	}
+	release, err := acquireLock(commander.lockFile(append(ancestors, app)))
+	if err != nil {
+		return err
+	}
+	defer release()
+	start := time.Now()
+	err = commander.executeCommand(ctx, app, cmd, arguments, timeout, inv)
+	if commander.OnCommandEnd != nil {
+		commander.OnCommandEnd(commandPath, arguments, time.Since(start), unwrapApplicationError(err))
 	}
+	if usage, ok := err.(UsageError); ok {
+		usage.Command, usage.Arguments = appname, append([]string{cmd}, arguments...)
+		usage.Hint = "usage: " + commandSynopsis(app, appname, cmd)
+		err = usage
+	}
+	if err != nil && !isApplicationError(err) {
+		commander.PrintUsageWithCommand(app, appname, cmd)
+		return errors.Wrap(err, "failed to run application")
+	} else if err != nil {
+		inner := err.(applicationError)
+		inv.appErr = inner.error
+		return inner.error
+	}
+	return nil
 }
 
 // GetFlagSet returns a flagset that corresponds to an application. This flagset can then be used
 // like a *flag.FlagSet, with the additional .Stringify method.
 func (commander Commander) GetFlagSet(app interface{}, appname string) (*FlagSet, error) {
-	flagset := flag.NewFlagSet(appname, commander.FlagErrorHandling)
-	flagset.SetOutput(commander.UsageOutput)
-	setter := newFlagSet(flagset)
+	setter := commander.newFlagSet(appname)
+	setter.path = []string{typeName(app)}
 	defer setter.finish()
 
 	if err := setupFlagSet(app, setter); err != nil {
@@ -159,86 +710,280 @@ func (commander Commander) GetFlagSet(app interface{}, appname string) (*FlagSet
 	return setter, nil
 }
 
+// newFlagSet returns an empty FlagSet configured like the Commander.
+func (commander Commander) newFlagSet(appname string) *FlagSet {
+	flagset := flag.NewFlagSet(appname, commander.FlagErrorHandling)
+	flagset.SetOutput(commander.UsageOutput)
+	setter := newFlagSet(flagset)
+	setter.shared = commander.AllowSharedFlags
+	setter.shadow = commander.ShadowFlags
+	setter.warnAliases = commander.WarnFlagAliases
+	setter.declarationOrder = commander.DeclarationOrder
+	setter.keepValues = commander.keepFlagValues
+	return setter
+}
+
 // GetFlagSetWithCommand returns a flagset that corresponds to an application. This flagset will
 // also contain the flagstruct setting sfor the given command of that application.
 func (commander Commander) GetFlagSetWithCommand(app interface{}, appname string, cmd string) (*FlagSet, error) {
-	appname = fmt.Sprintf("%s %s", appname, cmd)
-	flagset := flag.NewFlagSet(appname, commander.FlagErrorHandling)
-	flagset.SetOutput(commander.UsageOutput)
-	if err := setupNamedFlagStruct(app, cmd, flagset); err != nil {
-		return nil, err
+	return commander.commandFlagSet(map[string]interface{}{cmd: app}, appname, cmd)
+}
+
+// commandFlagSet returns the flagset of the command, made of the flagstructs bound to it by each of
+// the applications given. The applications are keyed by the path of the command from them, which
+// is the command itself for the application that implements it.
+func (commander Commander) commandFlagSet(apps map[string]interface{}, appname string, cmd string) (*FlagSet, error) {
+	setter := commander.newFlagSet(fmt.Sprintf("%s %s", appname, cmd))
+	setter.command = cmd
+	if provider, ok := apps[cmd].(LongDescriptionProvider); ok {
+		setter.description = provider.LongDescription(cmd)
 	}
-	return newFlagSet(flagset), nil
+	defer setter.finish()
+
+	paths := []string{}
+	for path := range apps {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		setter.path = []string{typeName(apps[path])}
+		if err := setupNamedFlagStruct(apps[path], path, setter); err != nil {
+			return nil, err
+		}
+	}
+	return setter, nil
 }
 
-func executeCommand(app interface{}, cmd string, args []string, flagset *flag.FlagSet) error {
+func (commander Commander) executeCommand(ctx context.Context, app interface{}, cmd string, args []string, timeout time.Duration, inv *invocation) error {
 	// Execute post flag parse hook
-	if err := executeHook(app); err != nil {
+	if err := executeHook(ctx, app); err != nil {
 		return errors.WithStack(err)
 	}
+	if inv.fallback {
+		if err := app.(FallbackHandler).CommanderFallback(cmd, args); err != nil {
+			return applicationError{err}
+		}
+		return nil
+	}
+
+	parent := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	method, in, injected, err := commander.commandInputs(ctx, app, cmd, args)
+	if err != nil {
+		return err
+	}
+	for _, param := range in[1+injected:] {
+		inv.arguments = append(inv.arguments, param.Interface())
+	}
 
 	// Finally run that command if everything seems fine
-	if err := runCommand(app, cmd, args...); err != nil {
+	if timeout > 0 {
+		return commander.callCommandWithTimeout(ctx, parent, cmd, timeout, method, in)
+	}
+	return commander.callCommand(ctx, method, in)
+}
+
+// callCommandWithTimeout calls the command like callCommand, and reports a TimeoutError if the
+// context created for its timeout is done by the time the command returns. Commands that take a
+// context are cancelled at that point, the others cannot be interrupted and are waited for, so that
+// no command is left running once the Commander returns.
+func (commander Commander) callCommandWithTimeout(ctx, parent context.Context, cmd string, timeout time.Duration, method reflect.Method, in []reflect.Value) error {
+	err := commander.callCommand(ctx, method, in)
+	if ctx.Err() == nil {
 		return err
+	} else if err := parent.Err(); err != nil {
+		return applicationError{err}
 	}
-	return nil
+	return applicationError{TimeoutError{Command: cmd, Timeout: timeout}}
 }
 
-// runCommand runs a specific command of the application with arguments.
-func runCommand(app interface{}, cmd string, args ...string) error {
+// commandInputs returns the method of the application that implements the command, the values
+// to call it with and how many of those values were injected rather than parsed from the
+// arguments. The leading parameters of the command of type context.Context, io.Reader and io.Writer
+// receive the context given and the Stdin and Stdout of the Commander.
+func (commander Commander) commandInputs(ctx context.Context, app interface{}, cmd string, args []string) (reflect.Method, []reflect.Value, int, error) {
 	method, err := getMethod(app, cmd)
 	if err != nil {
-		return err
+		return method, nil, 0, err
+	}
+
+	in := []reflect.Value{reflect.ValueOf(app)}
+	injections := map[reflect.Type]reflect.Value{
+		contextType: reflect.ValueOf(&ctx).Elem(),
+		readerType:  reflect.ValueOf(&commander.Stdin).Elem(),
+		writerType:  reflect.ValueOf(&commander.Stdout).Elem(),
+	}
+	for len(in) < method.Type.NumIn() {
+		value, found := injections[method.Type.In(len(in))]
+		if !found {
+			break
+		}
+		in = append(in, value)
+	}
+	injected := len(in) - 1
+
+	// Make sure we have enough args for this command. If the last parameter is a slice or a map, it
+	// collects all the extra arguments.
+	offset := len(in)
+	inputsize := method.Type.NumIn() - offset
+	last := method.Type.NumIn() - 1
+	trailing := inputsize > 0 && !utils.IsRawMessage(method.Type.In(last)) &&
+		(method.Type.In(last).Kind() == reflect.Slice || method.Type.In(last).Kind() == reflect.Map)
+	if provider, ok := app.(ArgumentCountProvider); ok {
+		if min, max := provider.GetArgumentCount(cmd); len(args) < min || (max >= 0 && len(args) > max) {
+			return method, nil, 0, argumentCountError(cmd, min, max, args)
+		}
+	}
+	if len(args) < inputsize-1 && trailing {
+		return method, nil, 0, arityError(cmd, inputsize-1, args)
+	} else if len(args) != inputsize && !trailing {
+		return method, nil, 0, arityError(cmd, inputsize, args)
 	}
 
-	// Make sure we have enough args for this command
-	inputsize := method.Type.NumIn() - 1
-	if len(args) < inputsize-1 && method.Type.In(inputsize).Kind() == reflect.Slice {
-		return fmt.Errorf("command requires %v arguments, have %v", inputsize-1, len(args))
-	} else if len(args) != inputsize && method.Type.In(inputsize).Kind() != reflect.Slice {
-		return fmt.Errorf("command requires %v arguments, have %v", inputsize, len(args))
-	} else if len(args) < inputsize {
-		args = append(args, "[]")
-	} else if len(args) > inputsize || method.Type.In(inputsize).Kind() == reflect.Slice {
-		// Then we consider that the extra arguments are just a list of strings
-		extras := args[inputsize-1:]
-		bytes, _ := json.Marshal(extras)
-		args[inputsize-1] = string(bytes)
-		args = args[:inputsize]
-	}
-
-	in := make([]reflect.Value, inputsize+1)
-	in[0] = reflect.ValueOf(app)
 	for i, arg := range args {
-		t := method.Type.In(i + 1)
+		if trailing && i == inputsize-1 {
+			break
+		}
+		t := method.Type.In(i + offset)
 		param, err := utils.ParseString(t, arg)
 		if err != nil {
-			return errors.Wrapf(err, "failed to parse string into function argument")
+			return method, nil, 0, errors.Wrapf(err, "failed to parse string into function argument")
 		}
-		in[i+1] = param
+		in = append(in, param)
+	}
+	if trailing {
+		extras, err := parseTrailingArguments(method.Type.In(last), args[inputsize-1:])
+		if err != nil {
+			return method, nil, 0, err
+		}
+		in = append(in, extras)
+	}
+	return method, in, injected, nil
+}
+
+// callCommand calls the method of the command with the values given. Commands can return an
+// error, an int exit code or both, in which case a non-zero exit code becomes an ExitError. They
+// can also return a channel or an iterator of values, optionally along with an error, in which
+// case each value is written to the Stdout of the Commander as it arrives, or a slice of structs,
+// optionally along with an error, which is written to the Stdout as a table.
+func (commander Commander) callCommand(ctx context.Context, method reflect.Method, in []reflect.Value) error {
+	var out []reflect.Value
+	if method.Type.IsVariadic() {
+		out = method.Func.CallSlice(in)
+	} else {
+		out = method.Func.Call(in)
 	}
-	out := method.Func.Call(in)
 	if len(out) == 0 {
 		return nil
+	} else if isStream(out[0].Type()) {
+		if len(out) > 1 {
+			if err, ok := out[1].Interface().(error); ok {
+				return applicationError{err}
+			}
+		}
+		if err := drainStream(ctx, commander.Stdout, out[0], commander.outputFormat); err != nil {
+			return applicationError{err}
+		}
+		return nil
+	} else if isTable(out[0].Type()) {
+		if len(out) > 1 {
+			if err, ok := out[1].Interface().(error); ok {
+				return applicationError{err}
+			}
+		}
+		if err := commander.writeRows(out[0]); err != nil {
+			return applicationError{err}
+		}
+		return nil
 	} else if err, ok := out[0].Interface().(error); ok {
 		return applicationError{err}
+	} else if out[0].Kind() != reflect.Int {
+		return nil
+	}
+
+	exit := ExitError{Code: int(out[0].Int())}
+	if len(out) > 1 {
+		exit.Err, _ = out[1].Interface().(error)
+	}
+	if exit.Code == 0 && exit.Err != nil {
+		return applicationError{exit.Err}
+	} else if exit.Code != 0 {
+		return applicationError{exit}
 	}
 	return nil
 }
 
-// subCommand returns the subcommand struct that corresponds to the command cmd. If none is found,
-// subCommand returns nil, nil.
-func subCommand(app interface{}, cmd string) (interface{}, error) {
+// parseTrailingArguments parses each of the extra arguments of a command into an element of the
+// slice or map type given.
+func parseTrailingArguments(t reflect.Type, extras []string) (reflect.Value, error) {
+	if t.Kind() == reflect.Map {
+		return parseKeyValueArguments(t, extras)
+	}
+
+	slice := reflect.MakeSlice(t, 0, len(extras))
+	for _, extra := range extras {
+		elem, err := utils.ParseString(t.Elem(), extra)
+		if err != nil {
+			return slice, errors.Wrapf(err, "failed to parse string into function argument")
+		} else if !elem.Type().ConvertibleTo(t.Elem()) {
+			return slice, fmt.Errorf("unsupported type for extra arguments: %v", t)
+		}
+		slice = reflect.Append(slice, elem.Convert(t.Elem()))
+	}
+	return slice, nil
+}
+
+// parseKeyValueArguments parses the extra arguments of a command into the map type given. The
+// arguments are either a single JSON object or any number of key=value pairs.
+func parseKeyValueArguments(t reflect.Type, extras []string) (reflect.Value, error) {
+	if len(extras) == 1 && strings.HasPrefix(strings.TrimSpace(extras[0]), "{") {
+		m := reflect.New(t)
+		if err := json.Unmarshal([]byte(extras[0]), m.Interface()); err != nil {
+			return m.Elem(), errors.Wrapf(err, "failed to parse string into function argument")
+		}
+		return m.Elem(), nil
+	}
+
+	m := reflect.MakeMap(t)
+	for _, extra := range extras {
+		split := strings.SplitN(extra, "=", 2)
+		if len(split) != 2 {
+			return m, fmt.Errorf("expected argument of the form key=value, got %v", extra)
+		}
+		key, err := utils.ParseString(t.Key(), split[0])
+		if err != nil {
+			return m, errors.Wrapf(err, "failed to parse key of argument %v", extra)
+		}
+		value, err := utils.ParseString(t.Elem(), split[1])
+		if err != nil {
+			return m, errors.Wrapf(err, "failed to parse value of argument %v", extra)
+		} else if !key.Type().ConvertibleTo(t.Key()) || !value.Type().ConvertibleTo(t.Elem()) {
+			return m, fmt.Errorf("unsupported type for extra arguments: %v", t)
+		}
+		m.SetMapIndex(key.Convert(t.Key()), value.Convert(t.Elem()))
+	}
+	return m, nil
+}
+
+// subCommand returns the subcommand struct that corresponds to the command cmd, along with the
+// directive that declared it. If none is found, subCommand returns a nil subcommand and no error.
+func subCommand(app interface{}, cmd string) (interface{}, subcommandDirective, error) {
+	var directive subcommandDirective
 	st, valid := utils.DerefType(app)
 	if !valid {
-		return nil, fmt.Errorf("application needs to be a struct or a pointer to a struct")
+		return nil, directive, fmt.Errorf("application needs to be a struct or a pointer to a struct")
 	}
 	for i := 0; i < st.NumField(); i++ {
 		field := st.Field(i)
 		if alias, ok := field.Tag.Lookup(FieldTag); ok && alias != "" {
 			split := strings.SplitN(alias, "=", 2)
 			if len(split) != 2 && (split[0] == FlagDirective || split[0] == SubcommandDirective) {
-				return nil, fmt.Errorf("malformed tag on application: %v", alias)
+				return nil, directive, fmt.Errorf("malformed tag on application: %v", alias)
 			}
 
 			// If this field has subflags, recurse inside that
@@ -247,35 +992,74 @@ func subCommand(app interface{}, cmd string) (interface{}, error) {
 			}
 
 			// Parse the directive to get the subcommand
-			subcmd, _ := parseSubcommandDirective(split[1])
-			if subcmd != cmd {
+			parsed, err := parseSubcommandDirective(split[1])
+			if parsed.cmd != cmd {
 				continue
+			} else if err != nil {
+				return nil, directive, err
+			} else if parsed.renamed != "" {
+				return renamedSubCommand(app, parsed)
 			}
 
 			// We have found the right subcommand
 			v, valid := utils.DerefValue(app)
 			if !valid || v.Kind() != reflect.Struct {
-				return nil, fmt.Errorf("failed to get subcommand from field %v of type %v", field.Name, st.Name())
+				return nil, directive, fmt.Errorf("failed to get subcommand from field %v of type %v", field.Name, st.Name())
 			}
 			fieldval := v.FieldByName(field.Name)
 			if !fieldval.IsValid() {
-				return nil, fmt.Errorf("failed to get subcommand from field %v of type %v", field.Name, st.Name())
+				return nil, directive, fmt.Errorf("failed to get subcommand from field %v of type %v", field.Name, st.Name())
 			}
-			return fieldval.Interface(), nil
+			subapp, err := addressField(fieldval, field, st)
+			return subapp, parsed, err
 		}
 	}
-	return nil, nil
+
+	if dynamic := dynamicSubcommand(app, cmd); dynamic != nil {
+		directive.cmd = cmd
+		return dynamic, directive, nil
+	}
+
+	if mountsPlugins(st) {
+		if plugin := registeredPlugin(cmd); plugin != nil {
+			directive.cmd = cmd
+			return plugin, directive, nil
+		}
+	}
+
+	if mounted := mountedSubcommand(app, cmd); mounted != nil {
+		directive.cmd = cmd
+		return mounted, directive, nil
+	}
+	return nil, directive, nil
 }
 
-func setupNamedFlagStruct(app interface{}, cmd string, flagset *flag.FlagSet) error {
+// renamedSubCommand returns the subcommand that the renamed one dispatches to. The directive
+// returned is the one of the new subcommand, with the deprecation markers of the old one.
+func renamedSubCommand(app interface{}, old subcommandDirective) (interface{}, subcommandDirective, error) {
+	subapp, directive, err := subCommand(app, old.renamed)
+	if err != nil {
+		return nil, directive, err
+	} else if subapp == nil {
+		return nil, directive, fmt.Errorf("subcommand %v was renamed to %v, which does not exist", old.cmd, old.renamed)
+	} else if directive.renamed != "" {
+		return nil, directive, fmt.Errorf("subcommand %v was renamed to %v, which was renamed too", old.cmd, old.renamed)
+	}
+	directive.deprecated, directive.renamed = old.deprecated, old.renamed
+	return subapp, directive, nil
+}
+
+func setupNamedFlagStruct(app interface{}, cmd string, setter *FlagSet) error {
 	// Get the raw type of the app
 	st, valid := utils.DerefType(app)
 	if !valid {
 		return fmt.Errorf("application needs to be a struct or a pointer to a struct")
 	}
-
-	setter := newFlagSet(flagset)
-	defer setter.finish()
+	leave, err := setter.enter(app)
+	if err != nil {
+		return err
+	}
+	defer leave()
 
 	// Look through each field for flags and subcommand flags
 	for i := 0; i < st.NumField(); i++ {
@@ -288,7 +1072,7 @@ func setupNamedFlagStruct(app interface{}, cmd string, flagset *flag.FlagSet) er
 		split := strings.SplitN(alias, "=", 2)
 		if len(split) != 2 || split[0] != FlagStructDirective {
 			continue
-		} else if normalizeCommand(split[1]) != normalizeCommand(cmd) {
+		} else if normalizeCommandPath(split[1]) != normalizeCommandPath(cmd) {
 			continue
 		}
 
@@ -296,7 +1080,7 @@ func setupNamedFlagStruct(app interface{}, cmd string, flagset *flag.FlagSet) er
 			return errors.Wrap(err, "failed to dereference flag struct")
 		} else if fieldIface == nil {
 			continue
-		} else if err := setupFlagSet(fieldIface, setter); err != nil {
+		} else if err := setter.nest(fieldIface, field.Name); err != nil {
 			return errors.Wrap(err, "failed to get flagset for sub-struct")
 		}
 	}
@@ -310,22 +1094,31 @@ func setupFlagSet(app interface{}, setter *FlagSet) error {
 	if !valid {
 		return fmt.Errorf("application needs to be a struct or a pointer to a struct")
 	}
+	leave, err := setter.enter(app)
+	if err != nil {
+		return err
+	}
+	defer leave()
+
+	setter.structs = append(setter.structs, app)
 
 	// Look through each field for flags and subcommand flags
 	for i := 0; i < st.NumField(); i++ {
 		field := st.Field(i)
 		if alias, ok := field.Tag.Lookup(FieldTag); ok && alias != "" {
-			split := strings.SplitN(alias, "=", 2)
-			if len(split) != 2 && (split[0] == FlagDirective || split[0] == SubcommandDirective) {
-				return fmt.Errorf("malformed tag on application: %v", alias)
-			}
-
 			// If this field is itself a flag
-			if split[0] == FlagDirective {
-				err := setter.setFlag(app, field, split[1])
-				if err != nil {
+			if options, isFlag, err := parseFlagTag(alias); err != nil {
+				return errors.Wrapf(err, "malformed tag on application: %v", alias)
+			} else if isFlag {
+				if err := setter.setFlag(app, field, options); err != nil {
 					return errors.Wrapf(err, "failed to setup flag for application")
 				}
+				continue
+			}
+
+			split := strings.SplitN(alias, "=", 2)
+			if len(split) != 2 && (split[0] == FlagDirective || split[0] == SubcommandDirective) {
+				return fmt.Errorf("malformed tag on application: %v", alias)
 			}
 
 			// If this field has subflags, recurse inside that
@@ -334,9 +1127,11 @@ func setupFlagSet(app interface{}, setter *FlagSet) error {
 					return errors.Wrap(err, "failed to dereference flag struct")
 				} else if fieldIface == nil {
 					continue
-				} else if err := setupFlagSet(fieldIface, setter); err != nil {
+				} else if err := setter.nest(fieldIface, field.Name); err != nil {
 					return errors.Wrap(err, "failed to get flagset for sub-struct")
 				}
+			} else if split[0] == EnvDirective && len(split) == 2 {
+				setter.setEnv(app, field, split[1])
 			} else if split[0] == FlagSliceDirective {
 				v, valid := utils.DerefValue(app)
 				if !valid || v.Kind() != reflect.Struct {
@@ -351,7 +1146,7 @@ func setupFlagSet(app interface{}, setter *FlagSet) error {
 				}
 				for i := 0; i < fieldval.Len(); i++ {
 					item := fieldval.Index(i)
-					if err := setupFlagSet(item.Interface(), setter); err != nil {
+					if err := setter.nest(item.Interface(), fmt.Sprintf("%v[%d]", field.Name, i)); err != nil {
 						return errors.Wrap(err, "failed to get flagset for slice element")
 					}
 				}
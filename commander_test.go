@@ -2,11 +2,17 @@ package commander_test
 
 import (
 	"bytes"
+	"context"
+	"flag"
+	"fmt"
 	"io/ioutil"
+	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/apourchet/commander"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -46,6 +52,18 @@ func TestCommanderInt(t *testing.T) {
 	require.Equal(t, 1, app.count)
 }
 
+func TestCommanderTimeArguments(t *testing.T) {
+	app := &Application{}
+	err := commander.New().RunCLI(app, []string{"opsince", "2024-01-01", "30s"})
+	require.NoError(t, err)
+	require.Equal(t, 1, app.count)
+
+	cmd := commander.New()
+	cmd.UsageOutput = ioutil.Discard
+	err = cmd.RunCLI(app, []string{"opsince", "2024-01-01", "30"})
+	require.Error(t, err)
+}
+
 func TestCommanderVariadic(t *testing.T) {
 	app := &Application{count: -5}
 	args := []string{"opvariadic", "a"}
@@ -64,6 +82,74 @@ func TestCommanderVariadic(t *testing.T) {
 	require.Equal(t, -1, app.count)
 }
 
+func TestCommanderTrailingArguments(t *testing.T) {
+	app := &Application{}
+	err := commander.New().RunCLI(app, []string{"opsum", "1", "2", "3"})
+	require.NoError(t, err)
+	require.Equal(t, 6, app.count)
+
+	err = commander.New().RunCLI(app, []string{"opsum"})
+	require.NoError(t, err)
+	require.Equal(t, 6, app.count)
+
+	err = commander.New().RunCLI(app, []string{"opspread", "a", `["b", "c"]`, "d"})
+	require.NoError(t, err)
+	require.Equal(t, 8, app.count)
+
+	cmd := commander.New()
+	cmd.UsageOutput = ioutil.Discard
+	err = cmd.RunCLI(app, []string{"opsum", "1", "two"})
+	require.Error(t, err)
+}
+
+func TestCommanderStdinArgument(t *testing.T) {
+	cmd := commander.New()
+	cmd.UsageOutput = ioutil.Discard
+	cmd.StdinArgument = true
+
+	app := &Application{}
+	cmd.Stdin = strings.NewReader("30\n")
+	err := cmd.RunCLI(app, []string{"optwo", "-"})
+	require.NoError(t, err)
+	require.Equal(t, 1, app.count)
+
+	cmd.Stdin = strings.NewReader("b\n")
+	err = cmd.RunCLI(app, []string{"opvariadic", "a", "-", "c"})
+	require.NoError(t, err)
+	require.Equal(t, 3, app.count)
+
+	cmd.Stdin = strings.NewReader("b")
+	err = cmd.RunCLI(app, []string{"opvariadic", "a", "-", "-"})
+	require.Error(t, err)
+
+	cmd.StdinArgument = false
+	err = cmd.RunCLI(app, []string{"opone", "-"})
+	require.NoError(t, err)
+	require.Equal(t, 3, app.count)
+}
+
+func TestCommanderNamedArguments(t *testing.T) {
+	cmd := commander.New()
+	cmd.UsageOutput = ioutil.Discard
+	cmd.NamedArguments = true
+
+	app := &CopyApplication{}
+	require.NoError(t, cmd.RunCLI(app, []string{"copy", "dst=./b", "src=./a"}))
+	require.Equal(t, "./a", app.src)
+	require.Equal(t, "./b", app.dst)
+
+	require.NoError(t, cmd.RunCLI(app, []string{"copy", "./c", "dst=./d"}))
+	require.Equal(t, "./c", app.src)
+	require.Equal(t, "dst=./d", app.dst)
+
+	require.Error(t, cmd.RunCLI(app, []string{"copy", "dst=./b"}))
+	require.Error(t, cmd.RunCLI(app, []string{"copy", "dst=./b", "dst=./c"}))
+
+	cmd.NamedArguments = false
+	require.NoError(t, cmd.RunCLI(app, []string{"copy", "dst=./b", "src=./a"}))
+	require.Equal(t, "dst=./b", app.src)
+}
+
 func TestCommanderSubcommand(t *testing.T) {
 	t.Run("1", func(t *testing.T) {
 		app := &Application{
@@ -95,6 +181,34 @@ func TestSubcommandArguments(t *testing.T) {
 	require.Equal(t, 1, app.SubApp.count)
 }
 
+func TestSubcommandKeyValueArguments(t *testing.T) {
+	app := &Application{
+		SubApp: &SubApplication{},
+	}
+	err := commander.New().RunCLI(app, []string{"subapp", "opfour", "test=testing", "env=prod"})
+	require.NoError(t, err)
+	require.Equal(t, 1, app.SubApp.count)
+
+	err = commander.New().RunCLI(app, []string{"subapp", "openv", "name", "a=1", "b=2"})
+	require.NoError(t, err)
+	require.Equal(t, 4, app.SubApp.count)
+
+	err = commander.New().RunCLI(app, []string{"subapp", "openv", "name", `{"a": 3}`})
+	require.NoError(t, err)
+	require.Equal(t, 7, app.SubApp.count)
+
+	err = commander.New().RunCLI(app, []string{"subapp", "openv", "name"})
+	require.NoError(t, err)
+	require.Equal(t, 7, app.SubApp.count)
+
+	cmd := commander.New()
+	cmd.UsageOutput = ioutil.Discard
+	err = cmd.RunCLI(app, []string{"subapp", "openv", "name", "a=b"})
+	require.Error(t, err)
+	err = cmd.RunCLI(app, []string{"subapp", "openv", "name", "a"})
+	require.Error(t, err)
+}
+
 func TestSubSubcommand(t *testing.T) {
 	app := &Application{
 		SubApp: &SubApplication{
@@ -220,6 +334,94 @@ Sub-Commands:
 	})
 }
 
+type DescribedApplication struct {
+	SyncFlags struct {
+		Force bool `commander:"flag=force,Overwrite the destination"`
+	} `commander:"flagstruct=sync"`
+}
+
+func (app *DescribedApplication) Sync(src, dst string) {}
+
+func (app *DescribedApplication) LongDescription(cmd string) string {
+	if cmd != "sync" {
+		return ""
+	}
+	return `Sync copies the files of the source directory to the destination directory, skipping the files
+that are already up to date.
+
+Examples:
+    sync /data /backup
+`
+}
+
+func TestUsageLongDescription(t *testing.T) {
+	expected := `Usage of CLI sync:
+
+  Sync copies the files of the source directory to the destination directory,
+  skipping the files that are already up to date.
+
+  Examples:
+      sync /data /backup
+
+  -force
+    	Overwrite the destination (type: bool, default: false)
+`
+	cmd := commander.New()
+	require.Contains(t, cmd.UsageWithCommand(&DescribedApplication{}, "sync"), expected)
+	require.NotContains(t, cmd.Usage(&DescribedApplication{}), "Examples")
+	require.NotContains(t, cmd.UsageWithCommand(&DescribedApplication{}, "longdescription"), "Examples")
+}
+
+type OrderedApplication struct {
+	Zone    string             `commander:"flag,name=zone,short=z,usage=Zone to deploy to"`
+	Apply   bool               `commander:"flag=apply,Apply the changes"`
+	Token   string             `commander:"flag,name=token,order=1,usage=API token"`
+	Status  *SubSubApplication `commander:"subcommand=status,Show the status"`
+	Deploy  *SubSubApplication `commander:"subcommand=deploy,Deploy the application,order=1"`
+	Rollout *SubSubApplication `commander:"subcommand=rollout,Roll out a release"`
+}
+
+func TestUsageOrder(t *testing.T) {
+	cmd := commander.New()
+	expected := `Usage of CLI:
+  -token
+    	API token (type: string, default: "")
+  -apply
+    	Apply the changes (type: bool, default: false)
+  -z	Short for -zone
+  -zone
+    	Zone to deploy to (type: string, default: "")
+
+Sub-Commands:
+  deploy  |  Deploy the application
+  rollout  |  Roll out a release
+  status  |  Show the status
+`
+	require.Equal(t, expected, cmd.Usage(&OrderedApplication{}))
+
+	cmd.DeclarationOrder = true
+	expected = `Usage of CLI:
+  -token
+    	API token (type: string, default: "")
+  -zone
+    	Zone to deploy to (type: string, default: "")
+  -z	Short for -zone
+  -apply
+    	Apply the changes (type: bool, default: false)
+
+Sub-Commands:
+  deploy  |  Deploy the application
+  status  |  Show the status
+  rollout  |  Roll out a release
+`
+	require.Equal(t, expected, cmd.Usage(&OrderedApplication{}))
+
+	_, err := cmd.GetFlagSet(&struct {
+		Zone string `commander:"flag,name=zone,order=first"`
+	}{}, "CLI")
+	require.Error(t, err)
+}
+
 func TestApplication2(t *testing.T) {
 	t.Run("calls_commander_default", func(t *testing.T) {
 		app := &Application2{
@@ -327,6 +529,211 @@ Usage of CLI cmd1:
 	})
 }
 
+func TestTimeout(t *testing.T) {
+	t.Run("directive", func(t *testing.T) {
+		app := &TimeoutApplication{Slow: &SlowApplication{}}
+		err := commander.New().RunCLI(app, []string{"slow", "sleep", "50ms"})
+		require.Error(t, err)
+		require.Equal(t, commander.TimeoutError{Command: "sleep", Timeout: 10 * time.Millisecond}, err)
+		require.True(t, app.Slow.slept)
+
+		err = commander.New().RunCLI(app, []string{"slow", "sleep", "1ms"})
+		require.NoError(t, err)
+	})
+
+	t.Run("flag_override", func(t *testing.T) {
+		app := &TimeoutApplication{Slow: &SlowApplication{}}
+		cmd := commander.New()
+		cmd.TimeoutFlag = true
+		err := cmd.RunCLI(app, []string{"--timeout", "1s", "slow", "sleep", "20ms"})
+		require.NoError(t, err)
+
+		err = cmd.RunCLI(app, []string{"--timeout", "5ms", "slow", "sleep", "50ms"})
+		require.Equal(t, commander.TimeoutError{Command: "sleep", Timeout: 5 * time.Millisecond}, err)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		app := &struct {
+			Slow *SlowApplication `commander:"subcommand=slow,timeout=soon"`
+		}{Slow: &SlowApplication{}}
+		err := commander.New().RunCLI(app, []string{"slow", "sleep", "1ms"})
+		require.Error(t, err)
+	})
+}
+
+func TestRunCLIContext(t *testing.T) {
+	ctx := context.WithValue(context.Background(), contextKey{}, "value")
+
+	t.Run("passes_context", func(t *testing.T) {
+		app := &ContextApplication{}
+		err := commander.New().RunCLIContext(ctx, app, []string{"run", "name"})
+		require.NoError(t, err)
+		require.Equal(t, "value", app.hooked)
+		require.Equal(t, "value", app.received)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		app := &ContextApplication{}
+		err := commander.New().RunCLIContext(canceled, app, []string{"run", "name"})
+		require.Equal(t, context.Canceled, err)
+		require.Nil(t, app.received)
+	})
+
+	t.Run("timeout_cancels_context", func(t *testing.T) {
+		app := &TimeoutApplication{Ctx: &ContextApplication{canceled: make(chan struct{})}}
+		err := commander.New().RunCLIContext(ctx, app, []string{"ctx", "wait"})
+		require.Error(t, err)
+		select {
+		case <-app.Ctx.canceled:
+		case <-time.After(time.Second):
+			require.Fail(t, "context of the command was not canceled")
+		}
+	})
+}
+
+func TestCommandHooks(t *testing.T) {
+	var started, ended []string
+	var startArgs, endArgs []string
+	var endErr error
+	cmd := commander.New()
+	cmd.UsageOutput = ioutil.Discard
+	cmd.OnCommandStart = func(path []string, args []string) {
+		started, startArgs = path, args
+	}
+	cmd.OnCommandEnd = func(path []string, args []string, duration time.Duration, err error) {
+		ended, endArgs, endErr = path, args, err
+		require.True(t, duration >= 5*time.Millisecond)
+	}
+
+	app := &TimeoutApplication{Slow: &SlowApplication{}}
+	err := cmd.RunCLI(app, []string{"slow", "sleep", "5ms"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"slow", "sleep"}, started)
+	require.Equal(t, []string{"slow", "sleep"}, ended)
+	require.Equal(t, []string{"5ms"}, startArgs)
+	require.Equal(t, []string{"5ms"}, endArgs)
+	require.NoError(t, endErr)
+
+	cmd.OnCommandEnd = func(path []string, args []string, duration time.Duration, err error) {
+		ended, endErr = path, err
+	}
+	err = cmd.RunCLI(&Application2{SubCmd2: &SubCmd2{}}, []string{"subcmd2", "cmd1"})
+	require.Error(t, err)
+	require.Equal(t, []string{"subcmd2", "cmd1"}, ended)
+	require.Error(t, endErr)
+
+	err = cmd.RunCLI(&Application{}, []string{"opthree"})
+	require.Equal(t, errTest, err)
+	require.Equal(t, errTest, endErr)
+}
+
+func TestParseHooks(t *testing.T) {
+	built, parsed := []string{}, []string{}
+	cmd := commander.New()
+	cmd.OnFlagSetBuilt = func(level int, flagset *commander.FlagSet) {
+		require.False(t, flagset.Parsed())
+		built = append(built, fmt.Sprintf("%d %v", level, flagset.Name()))
+	}
+	cmd.OnParsed = func(level int, flagset *commander.FlagSet) {
+		parsed = append(parsed, fmt.Sprintf("%d %v %v", level, flagset.Name(), flagset.StringifyChanged()))
+	}
+
+	app := &Application{SubApp: &SubApplication{}}
+	require.NoError(t, cmd.RunCLI(app, []string{"--intflag", "1", "subapp", "--subintflag", "2", "openv", "x"}))
+	require.Equal(t, []string{"0 myapp", "1 myapp subapp", "2 myapp subapp openv"}, built)
+	require.Equal(t, []string{"0 myapp [--intflag 1]", "1 myapp subapp [--subintflag 2]", "2 myapp subapp openv []"}, parsed)
+}
+
+func TestRunCLIString(t *testing.T) {
+	cmd := commander.New()
+	cmd.UsageOutput = ioutil.Discard
+
+	app := &Application{SubApp: &SubApplication{}}
+	require.NoError(t, cmd.RunCLIString(app, `--intflag 10 subapp opfour "test=testing" 'other=a b'`))
+	require.Equal(t, 10, app.IntFlag)
+	require.Equal(t, 1, app.SubApp.count)
+
+	require.NoError(t, cmd.RunCLIString(app, `opvariadic name 'a b' c\ d`))
+	require.Equal(t, 2, app.count)
+
+	err := cmd.RunCLIString(app, `opone 'test`)
+	require.EqualError(t, err, "failed to split command line: unterminated single quote")
+}
+
+func TestPreprocessArgs(t *testing.T) {
+	cmd := commander.New()
+	cmd.PreprocessArgs = func(arguments []string) []string {
+		if len(arguments) > 0 && arguments[0] == "one" {
+			arguments = append([]string{"opone"}, arguments[1:]...)
+		}
+		arguments[len(arguments)-1] = "test"
+		return append([]string{"--intflag=10"}, arguments...)
+	}
+
+	app := &Application{}
+	arguments := []string{"one", "legacy"}
+	require.NoError(t, cmd.RunCLI(app, arguments))
+	require.Equal(t, 10, app.IntFlag)
+	require.Equal(t, 1, app.count)
+	require.Equal(t, []string{"one", "legacy"}, arguments)
+}
+
+type ShadowHostApplication struct {
+	Out *FlagTesterShadowed `commander:"subcommand=out"`
+}
+
+func TestUnknownFlagSuggestions(t *testing.T) {
+	t.Run("same_command", func(t *testing.T) {
+		expected := `flag provided but not defined: -b3
+Usage of CLI cmd1:
+  -b2
+    	No usage found for this flag. (type: string, default: "")
+  -common
+    	No usage found for this flag. (type: string, default: "")
+did you mean -b2?
+`
+		buf := &bytes.Buffer{}
+		cmd := commander.New()
+		cmd.UsageOutput = buf
+		err := cmd.RunCLI(&Application3{}, []string{"cmd1", "--b3"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "did you mean -b2?")
+		assertEqualLines(t, expected, buf.String())
+
+		err = cmd.RunCLI(&Application3{}, []string{"cmd1", "--c2", "1"})
+		require.Contains(t, err.Error(), "did you mean -c2 (flag of command cmd2), -b2?")
+	})
+
+	t.Run("subcommand", func(t *testing.T) {
+		cmd := commander.New()
+		cmd.UsageOutput = ioutil.Discard
+		app := &Application{SubApp: &SubApplication{}}
+		err := cmd.RunCLI(app, []string{"--subintflag", "1", "subapp", "opthree"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "did you mean -subintflag (flag of subcommand subapp), -subintflag (flag of subcommand subapp2)?")
+	})
+
+	t.Run("shadowed_subcommand", func(t *testing.T) {
+		cmd := commander.New()
+		cmd.UsageOutput = ioutil.Discard
+		cmd.ShadowFlags = true
+		app := &ShadowHostApplication{Out: &FlagTesterShadowed{}}
+		err := cmd.RunCLI(app, []string{"--formt", "json", "out"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "did you mean -format (flag of subcommand out)?")
+	})
+
+	t.Run("nothing_close", func(t *testing.T) {
+		cmd := commander.New()
+		cmd.UsageOutput = ioutil.Discard
+		err := cmd.RunCLI(&Application{}, []string{"--nothingclose", "opthree"})
+		require.Error(t, err)
+		require.NotContains(t, err.Error(), "did you mean")
+	})
+}
+
 func assertEqualLines(t *testing.T, expected, actual string) {
 	swapped := false
 	small, big := strings.Split(expected, "\n"), strings.Split(actual, "\n")
@@ -352,3 +759,467 @@ func assertEqualLines(t *testing.T, expected, actual string) {
 		assert.Fail(t, symbol+big[i])
 	}
 }
+
+func TestAllowedCommands(t *testing.T) {
+	app := &AllowlistApplication{}
+	cmd := commander.New()
+	cmd.UsageOutput = ioutil.Discard
+	require.NoError(t, cmd.RunCLI(app, []string{"help"}))
+	require.True(t, app.Helped)
+	require.Error(t, cmd.RunCLI(app, []string{"helper"}))
+	require.Error(t, cmd.RunCLI(app, []string{"get-allowed-commands"}))
+}
+
+func TestCommandNames(t *testing.T) {
+	app := &RenamedApplication{}
+	cmd := commander.New()
+	cmd.UsageOutput = ioutil.Discard
+	require.Error(t, cmd.RunCLI(app, []string{"listall"}))
+	require.False(t, app.Listed)
+	require.NoError(t, cmd.RunCLI(app, []string{"ls"}))
+	require.True(t, app.Listed)
+}
+
+func TestDeprecatedSubcommands(t *testing.T) {
+	var buf bytes.Buffer
+	cmd := commander.New()
+	cmd.UsageOutput = &buf
+	app := &DeprecatingApplication{Sub: &SubSubApplication{}, Old: &SubSubApplication{}}
+
+	require.NoError(t, cmd.RunCLI(app, []string{"sub", "opdeep"}))
+	require.Empty(t, buf.String())
+
+	require.NoError(t, cmd.RunCLI(app, []string{"old", "opdeep"}))
+	require.Equal(t, "warning: subcommand old is deprecated\n", buf.String())
+	buf.Reset()
+
+	require.NoError(t, cmd.RunCLI(app, []string{"legacy", "opdeep"}))
+	require.Equal(t, "warning: subcommand legacy was renamed to sub\n", buf.String())
+	require.Equal(t, 2, app.Sub.count)
+	require.Equal(t, 1, app.Old.count)
+
+	expected := `Usage of CLI:
+
+Sub-Commands:
+  legacy  |  Renamed to sub
+  old  |  Use old commands (deprecated)
+  sub  |  Use sub commands
+`
+	assertEqualLines(t, expected, cmd.Usage(app))
+	require.NoError(t, commander.Validate(app))
+}
+
+func TestStrictOrdering(t *testing.T) {
+	cmd := commander.New()
+	cmd.UsageOutput = ioutil.Discard
+	app := &Application{SubApp: &SubApplication{}}
+	require.NoError(t, cmd.RunCLI(app, []string{"opvariadic", "a", "--intflag", "3"}))
+
+	cmd.StrictOrdering = true
+	err := cmd.RunCLI(app, []string{"opvariadic", "a", "--intflag", "3"})
+	require.Error(t, err)
+	require.Equal(t, "place --intflag before the arguments of command opvariadic", err.Error())
+
+	err = cmd.RunCLI(app, []string{"subapp", "openv", "a", "--subintflag=3"})
+	require.Error(t, err)
+	require.Equal(t, "place --subintflag before the arguments of command openv", err.Error())
+
+	require.NoError(t, cmd.RunCLI(app, []string{"--intflag", "3", "opvariadic", "a", "-5"}))
+	require.NoError(t, cmd.RunCLI(app, []string{"opvariadic", "--", "a", "--intflag"}))
+	require.NoError(t, cmd.RunCLI(app, []string{"opvariadic", "a", "--", "--intflag"}))
+}
+
+func TestFlagStructCommandPath(t *testing.T) {
+	cmd := commander.New()
+	cmd.UsageOutput = ioutil.Discard
+	app := &ScopingApplication{Manage: &CopyApplication{}}
+	require.NoError(t, cmd.RunCLI(app, []string{"manage", "copy", "--force", "a", "b"}))
+	require.True(t, app.Copy.Force)
+	require.Equal(t, "a", app.Manage.src)
+	require.Equal(t, "b", app.Manage.dst)
+
+	require.Error(t, cmd.RunCLI(&ScopingApplication{Manage: &CopyApplication{}}, []string{"--force", "manage", "copy", "a", "b"}))
+	require.NoError(t, commander.Validate(app))
+}
+
+func TestValidator(t *testing.T) {
+	cmd := commander.New()
+	cmd.UsageOutput = ioutil.Discard
+
+	app := &ValidatedApplication{}
+	err := cmd.RunCLI(app, []string{"--cert", "c", "serve"})
+	require.Error(t, err)
+	require.Equal(t, "invalid flags: --port must be positive; either --cert and --key or neither", err.Error())
+	require.False(t, app.served)
+
+	require.NoError(t, cmd.RunCLI(app, []string{"--port", "80", "--cert", "c", "--key", "k", "serve"}))
+	require.True(t, app.served)
+}
+
+func TestStreamInjection(t *testing.T) {
+	var out bytes.Buffer
+	cmd := commander.New()
+	cmd.Stdin = strings.NewReader("hello")
+	cmd.Stdout = &out
+
+	report, err := cmd.RunReport(&StreamApplication{}, []string{"upper", "> "})
+	require.NoError(t, err)
+	require.Equal(t, "> HELLO", out.String())
+	require.Equal(t, []interface{}{"> "}, report.Arguments)
+}
+
+func TestRawMessageArguments(t *testing.T) {
+	cmd := commander.New()
+	cmd.UsageOutput = ioutil.Discard
+	app := &PayloadApplication{}
+	require.NoError(t, cmd.RunCLI(app, []string{"--meta", `[1, "two"]`, "send", `{"id": 3}`}))
+	require.Equal(t, `[1, "two"]`, string(app.Meta))
+	require.Equal(t, `{"id": 3}`, string(app.sent))
+
+	require.Error(t, cmd.RunCLI(app, []string{"send", `{"id":`}))
+	require.Error(t, cmd.RunCLI(app, []string{"send", "{}", "{}"}))
+}
+
+func TestCommandPath(t *testing.T) {
+	cmd := commander.New()
+	shared := &PathApplication{}
+	app := &PathApplication{Left: shared, Right: shared}
+
+	require.NoError(t, cmd.RunCLI(app, []string{"left", "run"}))
+	require.Equal(t, []string{}, app.path)
+	require.Equal(t, []string{"left", "run"}, shared.path)
+	require.Equal(t, []string{"left", "run"}, shared.hookPath)
+
+	require.NoError(t, cmd.RunCLI(app, []string{"right", "run"}))
+	require.Equal(t, []string{"right", "run"}, shared.hookPath)
+}
+
+func TestDefaultCLIName(t *testing.T) {
+	defer func(name string) { os.Args[0] = name }(os.Args[0])
+	os.Args[0] = "/usr/local/bin/petstore"
+
+	cmd := commander.New()
+	require.True(t, strings.HasPrefix(cmd.Usage(&SubCmd2{}), "Usage of petstore:\n"))
+	require.True(t, strings.HasPrefix(cmd.Usage(&Application{}), "Usage of myapp:\n"))
+}
+
+func TestQuotedSubcommandDescriptions(t *testing.T) {
+	app := &struct {
+		Sub *SubSubApplication `commander:"subcommand=sub,'Runs, then stops,deprecated'"`
+		Old *SubSubApplication `commander:"subcommand=old,Waits for timeout\\=1s,timeout=1s"`
+	}{}
+	expected := `Usage of CLI:
+
+Sub-Commands:
+  old  |  Waits for timeout=1s
+  sub  |  Runs, then stops,deprecated
+`
+	assertEqualLines(t, expected, commander.New().Usage(app))
+}
+
+type ValueFieldsApplication struct {
+	Sub   SubSubApplication `commander:"subcommand=sub"`
+	Flags struct {
+		Force bool `commander:"flag=force"`
+	} `commander:"flagstruct"`
+}
+
+func TestValueTypedFields(t *testing.T) {
+	cmd := commander.New()
+	cmd.UsageOutput = ioutil.Discard
+	app := &ValueFieldsApplication{}
+	require.NoError(t, cmd.RunCLI(app, []string{"--force", "sub", "opdeep"}))
+	require.NoError(t, cmd.RunCLI(app, []string{"sub", "opdeep"}))
+	require.Equal(t, 2, app.Sub.count)
+	require.True(t, app.Flags.Force)
+
+	err := cmd.RunCLI(ValueFieldsApplication{}, []string{"sub", "opdeep"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "pass a pointer to the ValueFieldsApplication application instead")
+}
+
+type ExitingApplication struct{}
+
+func (app *ExitingApplication) Check(code int) int { return code }
+
+func (app *ExitingApplication) Fail(code int) (int, error) { return code, errTest }
+
+func TestExitCodes(t *testing.T) {
+	cmd := commander.New()
+	cmd.UsageOutput = ioutil.Discard
+	app := &ExitingApplication{}
+
+	err := cmd.RunCLI(app, []string{"check", "0"})
+	require.NoError(t, err)
+	require.Equal(t, 0, commander.ExitCode(err))
+
+	err = cmd.RunCLI(app, []string{"check", "3"})
+	require.EqualError(t, err, "exit status 3")
+	require.Equal(t, 3, commander.ExitCode(err))
+
+	err = cmd.RunCLI(app, []string{"fail", "4"})
+	require.Equal(t, errTest, errors.Cause(err).(commander.ExitError).Err)
+	require.Equal(t, 4, commander.ExitCode(err))
+
+	err = cmd.RunCLI(app, []string{"fail", "0"})
+	require.Equal(t, errTest, err)
+	require.Equal(t, 1, commander.ExitCode(err))
+
+	require.Equal(t, 1, commander.ExitCode(cmd.RunCLI(app, []string{"unknown"})))
+}
+
+func TestSlashFlags(t *testing.T) {
+	cmd := commander.New()
+	cmd.UsageOutput = ioutil.Discard
+	cmd.SlashFlags = true
+
+	app := &Application{SubApp: &SubApplication{}}
+	require.NoError(t, cmd.RunCLI(app, []string{"/intflag", "10", "subapp", "/subintflag:3", "openv", "/tmp/file"}))
+	require.Equal(t, 10, app.IntFlag)
+	require.Equal(t, 3, app.SubApp.SubIntFlag)
+
+	app = &Application{}
+	require.NoError(t, cmd.RunCLI(app, []string{"/intflag:2", "opone", "/notaflag"}))
+	require.Equal(t, 2, app.IntFlag)
+	require.Equal(t, flag.ErrHelp, errors.Cause(cmd.RunCLI(app, []string{"/?"})))
+
+	copier := &SlashCopyApplication{}
+	require.NoError(t, cmd.RunCLI(copier, []string{"/tmp:x", "cp", "/tmp"}))
+	require.Equal(t, "x", copier.Tmp)
+	require.Equal(t, "/tmp", copier.src)
+
+	copier = &SlashCopyApplication{}
+	require.NoError(t, cmd.RunCLI(copier, []string{"/tmp", "/tmp", "cp", "/tmp"}))
+	require.Equal(t, "/tmp", copier.Tmp)
+	require.Equal(t, "/tmp", copier.src)
+
+	cmd.SlashFlags = false
+	require.Error(t, cmd.RunCLI(&Application{}, []string{"/intflag", "10", "opone", "test"}))
+}
+
+type SlashCopyApplication struct {
+	Tmp string `commander:"flag=tmp"`
+
+	src string
+}
+
+func (app *SlashCopyApplication) Cp(src string) { app.src = src }
+
+type StoreApplication struct {
+	StoreLocation string `commander:"flag=store-location,Where to store"`
+	StoreType     string `commander:"flag=store-type,What to store in"`
+	Output        string `commander:"flag=output,alias=outfile"`
+	Stream        bool   `commander:"flag=stream"`
+
+	args []string
+}
+
+func (app *StoreApplication) Put(args []string) { app.args = args }
+
+func TestAbbreviatedFlags(t *testing.T) {
+	cmd := commander.New()
+	cmd.UsageOutput = ioutil.Discard
+	cmd.AbbreviatedFlags = true
+
+	app := &StoreApplication{}
+	require.NoError(t, cmd.RunCLI(app, []string{"--store-l", "/tmp", "--store-t=disk", "-out", "-stream", "--str", "put", "--", "--o"}))
+	require.Equal(t, "/tmp", app.StoreLocation)
+	require.Equal(t, "disk", app.StoreType)
+	require.Equal(t, "-stream", app.Output)
+	require.Equal(t, []string{"--o"}, app.args)
+
+	err := cmd.RunCLI(&StoreApplication{}, []string{"--sto=/tmp", "put"})
+	require.EqualError(t, errors.Cause(err), "ambiguous flag --sto; it could be any of --store-location, --store-type")
+	err = cmd.RunCLI(&StoreApplication{}, []string{"-s", "put"})
+	require.EqualError(t, errors.Cause(err), "ambiguous flag -s; it could be any of -store-location, -store-type, -stream")
+
+	host := &StoreHostApplication{Sub: &StoreSubApplication{}}
+	require.NoError(t, cmd.RunCLI(host, []string{"--store-l", "/tmp", "sub", "--store", "x", "--verb", "run"}))
+	require.Equal(t, "/tmp", host.StoreLocation)
+	require.Equal(t, "x", host.Sub.Store)
+	require.True(t, host.Sub.Verbose)
+	require.True(t, host.Sub.ran)
+
+	cmd.AbbreviatedFlags = false
+	require.Error(t, cmd.RunCLI(&StoreApplication{}, []string{"--store-l", "/tmp", "put"}))
+}
+
+type StoreHostApplication struct {
+	StoreLocation string               `commander:"flag=store-location,Where to store"`
+	Sub           *StoreSubApplication `commander:"subcommand=sub"`
+}
+
+type StoreSubApplication struct {
+	Store   string `commander:"flag=store"`
+	Verbose bool   `commander:"flag=verbose"`
+
+	ran bool
+}
+
+func (app *StoreSubApplication) Run() { app.ran = true }
+
+func TestWarnIrrelevantFlags(t *testing.T) {
+	var buf bytes.Buffer
+	cmd := commander.New()
+	cmd.UsageOutput = &buf
+
+	app := &Application3{}
+	require.Error(t, cmd.RunCLI(app, []string{"cmd2", "--b2", "x", "3"}))
+
+	buf.Reset()
+	cmd.WarnIrrelevantFlags = true
+	require.NoError(t, cmd.RunCLI(app, []string{"cmd2", "--b2", "x", "--c2=y", "--common=z", "3"}))
+	require.Equal(t, "", app.B.B2)
+	require.Equal(t, "y", app.C.C2)
+	require.Equal(t, "z", app.C.C1)
+	require.Equal(t, "warning: flag -b2 is a flag of command cmd1, ignoring it for command cmd2\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, cmd.RunCLI(app, []string{"cmd1", "--b2=x", "a"}))
+	require.Equal(t, "x", app.B.B2)
+	require.Empty(t, buf.String())
+
+	require.Error(t, cmd.RunCLI(app, []string{"cmd2", "--unknown", "3"}))
+	require.Error(t, cmd.RunCLI(app, []string{"--b2", "x", "cmd2", "3"}))
+}
+
+type BoundedApplication struct {
+	names []string
+}
+
+func (app *BoundedApplication) Tag(names []string) { app.names = names }
+
+func (app *BoundedApplication) Untag(names []string) { app.names = names }
+
+func (app *BoundedApplication) GetArgumentCount(cmd string) (int, int) {
+	if cmd == "tag" {
+		return 1, 2
+	}
+	return 1, -1
+}
+
+func TestArgumentCount(t *testing.T) {
+	cmd := commander.New()
+	cmd.UsageOutput = ioutil.Discard
+	app := &BoundedApplication{}
+
+	require.NoError(t, cmd.RunCLI(app, []string{"tag", "a", "b"}))
+	require.Equal(t, []string{"a", "b"}, app.names)
+
+	err := cmd.RunCLI(app, []string{"tag", "a", "b", "c"})
+	require.EqualError(t, err, "failed to run application: command tag takes between 1 and 2 arguments, have 3; usage: CLI tag [<string>...]")
+	require.Equal(t, "c", errors.Cause(err).(commander.UsageError).Token)
+
+	err = cmd.RunCLI(app, []string{"untag"})
+	require.EqualError(t, err, "failed to run application: command untag takes at least 1 arguments, have 0; usage: CLI untag [<string>...]")
+	require.NoError(t, cmd.RunCLI(app, []string{"untag", "a", "b", "c"}))
+}
+
+type FallbackApplication struct {
+	cmd    string
+	args   []string
+	hooked bool
+	paths  [][]string
+
+	Region string `commander:"flag=region"`
+}
+
+func (app *FallbackApplication) List() {}
+
+func (app *FallbackApplication) PostFlagParse() error {
+	app.hooked = true
+	return nil
+}
+
+func (app *FallbackApplication) Validate() error {
+	if app.Region == "nowhere" {
+		return fmt.Errorf("unknown region %v", app.Region)
+	}
+	return nil
+}
+
+func (app *FallbackApplication) PreDispatch(path []string, args []string) error {
+	app.paths = append(app.paths, path)
+	if len(path) > 0 && path[len(path)-1] == "forbidden" {
+		return errors.New("forbidden is not allowed")
+	}
+	return nil
+}
+
+func (app *FallbackApplication) CommanderFallback(cmd string, args []string) error {
+	if cmd == "missing" {
+		return errTest
+	}
+	app.cmd, app.args = cmd, args
+	return nil
+}
+
+func TestFallbackHandler(t *testing.T) {
+	cmd := commander.New()
+	cmd.UsageOutput = ioutil.Discard
+	app := &FallbackApplication{}
+
+	require.NoError(t, cmd.RunCLI(app, []string{"list"}))
+	require.Empty(t, app.cmd)
+
+	require.NoError(t, cmd.RunCLI(app, []string{"my-bucket", "ls", "-l"}))
+	require.Equal(t, "my-bucket", app.cmd)
+	require.Equal(t, []string{"ls", "-l"}, app.args)
+
+	require.Equal(t, errTest, cmd.RunCLI(app, []string{"missing"}))
+	require.Error(t, cmd.RunCLI(app, []string{}))
+}
+
+func TestFallbackHandlerDispatch(t *testing.T) {
+	var started, ended []string
+	cmd := commander.New()
+	cmd.UsageOutput = ioutil.Discard
+	cmd.OnCommandStart = func(path []string, args []string) { started = path }
+	cmd.OnCommandEnd = func(path []string, args []string, duration time.Duration, err error) { ended = path }
+
+	app := &FallbackApplication{}
+	require.NoError(t, cmd.RunCLI(app, []string{"--region", "eu", "my-bucket", "ls"}))
+	require.Equal(t, "my-bucket", app.cmd)
+	require.True(t, app.hooked)
+	require.Equal(t, [][]string{{"my-bucket"}}, app.paths)
+	require.Equal(t, []string{"my-bucket"}, started)
+	require.Equal(t, []string{"my-bucket"}, ended)
+
+	app = &FallbackApplication{}
+	require.EqualError(t, cmd.RunCLI(app, []string{"--region", "nowhere", "my-bucket"}), "invalid flags: unknown region nowhere")
+	require.EqualError(t, cmd.RunCLI(app, []string{"forbidden"}), "forbidden is not allowed")
+	require.Empty(t, app.cmd)
+	require.False(t, app.hooked)
+}
+
+type GatedApplication struct {
+	paths [][]string
+	args  [][]string
+
+	IntFlag int             `commander:"flag=intflag"`
+	SubApp  *SubApplication `commander:"subcommand=subapp"`
+}
+
+func (app *GatedApplication) OpThree() {}
+
+func (app *GatedApplication) PreDispatch(path []string, args []string) error {
+	app.paths, app.args = append(app.paths, path), append(app.args, args)
+	if len(path) > 0 && path[len(path)-1] == "opthree" {
+		return errors.New("opthree is not allowed")
+	}
+	return nil
+}
+
+func TestPreDispatchHook(t *testing.T) {
+	cmd := commander.New()
+	cmd.UsageOutput = ioutil.Discard
+	app := &GatedApplication{SubApp: &SubApplication{}}
+
+	require.NoError(t, cmd.RunCLI(app, []string{"--intflag", "1", "subapp", "openv", "a", "x=1"}))
+	require.Equal(t, [][]string{{"subapp", "openv"}}, app.paths)
+	require.Equal(t, [][]string{{"a", "x=1"}}, app.args)
+	require.Equal(t, 1, app.SubApp.count)
+
+	require.EqualError(t, cmd.RunCLI(app, []string{"opthree"}), "opthree is not allowed")
+	require.Equal(t, []string{"opthree"}, app.paths[1])
+}
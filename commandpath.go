@@ -0,0 +1,118 @@
+package commander
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// RunCommandPath runs the command at the end of the path given, made of the subcommands that lead to
+// it, with the positional arguments and the flag values given, without going through a command line.
+// Every flag is set at the first level of the path that defines it, and the command fails if no level
+// defines it. The arguments are never parsed as flags. A path that ends with a subcommand runs its
+// default command, if it has one. Like with RunCLI, the hooks of the levels of the path, the
+// validators of the flagstructs and the lock of the application apply, but the command line features
+// of the Commander, like PreprocessArgs, the help and find commands or the --print-config flag, do
+// not. Of the flags that the Commander adds, only the timeout flag can be given.
+func (commander Commander) RunCommandPath(app interface{}, path []string, args []string, flags map[string]string) error {
+	ctx := context.Background()
+	names := make([]string, 0, len(flags))
+	for name := range flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	assigned := map[string]bool{}
+	setFlags := func(flagset *FlagSet) error {
+		for _, name := range names {
+			if assigned[name] || flagset.Lookup(name) == nil {
+				continue
+			} else if err := flagset.Set(name, flags[name]); err != nil {
+				return errors.Wrapf(err, "invalid value %q for flag -%v", flags[name], name)
+			}
+			assigned[name] = true
+		}
+		return flagset.parse(nil)
+	}
+
+	root, appname := app, getCLIName(app)
+	commandPath, ancestors, parsed := []string{}, []interface{}{}, []interface{}{}
+	var timeout, timeoutOverride time.Duration
+	for {
+		if err := commander.inject(app); err != nil {
+			return err
+		}
+		flagset, err := commander.GetFlagSet(app, appname)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if commander.TimeoutFlag && len(ancestors) == 0 && flagset.Lookup(TimeoutFlagName) == nil {
+			flagset.DurationVar(&timeoutOverride, TimeoutFlagName, 0, "Maximum duration of the command")
+		}
+		if err := setFlags(flagset); err != nil {
+			return err
+		}
+		parsed = append(parsed, flagset.structs...)
+
+		cmd := ""
+		if len(path) == 0 {
+			if cmd, err = findCommand(app, []string{DefaultCommand}); err != nil {
+				return err
+			} else if cmd == "" {
+				return fmt.Errorf("the command path of %v does not end with a command", appname)
+			}
+		} else if subapp, directive, err := subCommand(app, path[0]); err != nil {
+			return err
+		} else if subapp != nil {
+			setCommandPath(app, commandPath)
+			if err := executeHook(ctx, app); err != nil {
+				return errors.WithStack(err)
+			}
+			if directive.timeout != 0 {
+				timeout = directive.timeout
+			}
+			commandPath, ancestors = append(commandPath, directive.cmd), append(ancestors, app)
+			app, path = subapp, path[1:]
+			appname = getCLIName(root, commandPath...)
+			continue
+		} else if found, err := hasCommand(app, path[0]); err != nil {
+			return err
+		} else if !found {
+			return fmt.Errorf("%v is neither a subcommand nor a command of %v", path[0], appname)
+		} else if len(path) > 1 {
+			return fmt.Errorf("command %v of %v is followed by %v in the command path", path[0], appname, strings.Join(path[1:], " "))
+		} else {
+			cmd = path[0]
+			commandPath = append(commandPath, cmd)
+		}
+
+		if err := preDispatch(root, commandPath, args); err != nil {
+			return err
+		}
+
+		scoped := map[string]interface{}{cmd: app}
+		for i, ancestor := range ancestors {
+			scoped[strings.Join(append(commandPath[i:len(ancestors):len(ancestors)], cmd), " ")] = ancestor
+		}
+		if flagset, err = commander.commandFlagSet(scoped, appname, cmd); err != nil {
+			return fmt.Errorf("failed to setup flags: %v", err)
+		} else if err := setFlags(flagset); err != nil {
+			return err
+		}
+		for _, name := range names {
+			if !assigned[name] {
+				return fmt.Errorf("flag -%v is not defined by any level of the command path of %v", name, appname)
+			}
+		}
+		parsed = append(parsed, flagset.structs...)
+
+		if timeoutOverride != 0 {
+			timeout = timeoutOverride
+		}
+		return commander.dispatch(ctx, app, ancestors, appname, commandPath, cmd, args, parsed, timeout, &invocation{})
+	}
+}
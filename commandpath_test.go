@@ -0,0 +1,71 @@
+package commander_test
+
+import (
+	"testing"
+
+	"github.com/apourchet/commander"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunCommandPath(t *testing.T) {
+	app := &Application{SubApp: &SubApplication{}}
+	err := commander.New().RunCommandPath(app, []string{"subapp", "opfour"}, []string{"test=testing"},
+		map[string]string{"intflag": "10", "subintflag": "3"})
+	require.NoError(t, err)
+	require.Equal(t, 10, app.IntFlag)
+	require.Equal(t, 3, app.SubApp.SubIntFlag)
+	require.Equal(t, 1, app.SubApp.count)
+
+	app = &Application{}
+	require.NoError(t, commander.New().RunCommandPath(app, []string{"opvariadic"}, []string{"name", "-a", "--b"}, nil))
+	require.Equal(t, 2, app.count)
+}
+
+func TestRunCommandPathDispatch(t *testing.T) {
+	cmd := commander.New()
+	cmd.PreprocessArgs = func(arguments []string) []string { return []string{"help"} }
+
+	app := &Application2{SubCmd2: &SubCmd2{}}
+	require.NoError(t, cmd.RunCommandPath(app, []string{"subcmd2"}, []string{"arg"}, map[string]string{"anint": "3"}))
+	require.Equal(t, 3, app.SubCmd2.Fl)
+
+	gated := &GatedApplication{SubApp: &SubApplication{}}
+	err := cmd.RunCommandPath(gated, []string{"subapp", "openv"}, []string{"a", "x=1"}, map[string]string{"intflag": "1"})
+	require.NoError(t, err)
+	require.Equal(t, 1, gated.IntFlag)
+	require.Equal(t, 1, gated.SubApp.count)
+	require.Equal(t, [][]string{{"subapp", "openv"}}, gated.paths)
+
+	err = cmd.RunCommandPath(gated, []string{"opthree"}, nil, nil)
+	require.EqualError(t, err, "opthree is not allowed")
+}
+
+func TestRunCommandPathScopedFlags(t *testing.T) {
+	app := &ScopingApplication{Manage: &CopyApplication{}}
+	err := commander.New().RunCommandPath(app, []string{"manage", "copy"}, []string{"src", "dst"},
+		map[string]string{"force": "true"})
+	require.NoError(t, err)
+	require.True(t, app.Copy.Force)
+	require.Equal(t, "dst", app.Manage.dst)
+}
+
+func TestRunCommandPathErrors(t *testing.T) {
+	cmd := commander.New()
+	app := &Application{SubApp: &SubApplication{}}
+	err := cmd.RunCommandPath(app, []string{"subapp", "nope"}, nil, nil)
+	require.EqualError(t, err, "nope is neither a subcommand nor a command of myapp subapp")
+
+	err = cmd.RunCommandPath(app, []string{"opone", "subapp"}, nil, nil)
+	require.EqualError(t, err, "command opone of myapp is followed by subapp in the command path")
+
+	err = cmd.RunCommandPath(app, []string{"subapp"}, nil, nil)
+	require.EqualError(t, err, "the command path of myapp subapp does not end with a command")
+
+	err = cmd.RunCommandPath(app, []string{"opone"}, []string{"test"}, map[string]string{"unknown": "1"})
+	require.EqualError(t, err, "flag -unknown is not defined by any level of the command path of myapp")
+	require.Equal(t, 0, app.count)
+
+	err = cmd.RunCommandPath(app, []string{"opone"}, []string{"test"}, map[string]string{"intflag": "ten"})
+	require.Error(t, err)
+	require.Equal(t, 0, app.count)
+}
@@ -0,0 +1,127 @@
+package commander
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+
+	"github.com/apourchet/commander/utils"
+)
+
+// DoctorCommand is the command that the Commander adds to the application when Doctor is set.
+const DoctorCommand = "doctor"
+
+// RunDoctor checks how the application is wired and writes a report of what it found to the
+// writer. On top of the problems that Validate finds with the tags, it reports the subcommands that
+// are nil and the flags that fail to be set up, like duplicate flags. Subcommands without a
+// description and flags without a usage are reported as warnings. The problems are returned as a
+// ValidationError, which leaves out the warnings.
+func (commander Commander) RunDoctor(w io.Writer, app interface{}) error {
+	doctor := &doctor{commander: commander, visited: map[interface{}]bool{}}
+	doctor.commander.UsageOutput = ioutil.Discard
+	if err := Validate(app); err != nil {
+		doctor.problems = append(doctor.problems, err.(ValidationError).Problems...)
+	}
+	doctor.check(app, getCLIName(app))
+
+	for _, problem := range doctor.problems {
+		fmt.Fprintf(w, "error: %v\n", problem)
+	}
+	for _, warning := range doctor.warnings {
+		fmt.Fprintf(w, "warning: %v\n", warning)
+	}
+	if len(doctor.problems)+len(doctor.warnings) == 0 {
+		fmt.Fprintf(w, "no problems found\n")
+	} else {
+		fmt.Fprintf(w, "%v errors, %v warnings\n", len(doctor.problems), len(doctor.warnings))
+	}
+
+	if len(doctor.problems) > 0 {
+		return ValidationError{Problems: doctor.problems}
+	}
+	return nil
+}
+
+type doctor struct {
+	commander Commander
+	visited   map[interface{}]bool
+	problems  []string
+	warnings  []string
+}
+
+// check checks the application and its subcommands, the application being named appname.
+func (doctor *doctor) check(app interface{}, appname string) {
+	if reflect.ValueOf(app).Kind() == reflect.Ptr {
+		if doctor.visited[app] {
+			return
+		}
+		doctor.visited[app] = true
+	}
+
+	flagset, err := doctor.commander.GetFlagSet(app, appname)
+	if err != nil {
+		doctor.problems = append(doctor.problems, fmt.Sprintf("%v: %v", appname, err))
+	} else {
+		doctor.checkUsages(appname, flagset)
+	}
+	for _, cmd := range commandNames(app) {
+		if cmdset, err := doctor.commander.GetFlagSetWithCommand(app, appname, cmd); err != nil {
+			doctor.problems = append(doctor.problems, fmt.Sprintf("%v %v: %v", appname, cmd, err))
+		} else {
+			doctor.checkUsages(appname+" "+cmd, cmdset)
+		}
+	}
+
+	st, valid := utils.DerefType(app)
+	if !valid {
+		return
+	}
+	provider, _ := app.(CommandDescriptionProvider)
+	for i := 0; i < st.NumField(); i++ {
+		split := strings.SplitN(st.Field(i).Tag.Get(FieldTag), "=", 2)
+		if len(split) != 2 || split[0] != SubcommandDirective {
+			continue
+		}
+		directive, _ := parseSubcommandDirective(split[1])
+		if directive.renamed != "" {
+			continue
+		} else if directive.description == "" && (provider == nil || provider.GetCommandDescription(directive.cmd) == "") {
+			doctor.warnings = append(doctor.warnings, fmt.Sprintf("%v: subcommand %v has no description", appname, directive.cmd))
+		}
+
+		subapp, _, err := subCommand(app, directive.cmd)
+		if v := reflect.ValueOf(subapp); err != nil {
+			doctor.problems = append(doctor.problems, fmt.Sprintf("%v: %v", appname, err))
+		} else if subapp == nil || (v.Kind() == reflect.Ptr && v.IsNil()) {
+			doctor.problems = append(doctor.problems, fmt.Sprintf("%v: subcommand %v is nil", appname, directive.cmd))
+		} else {
+			doctor.check(subapp, appname+" "+directive.cmd)
+		}
+	}
+	for _, cmd := range dynamicSubcommandNames(app) {
+		doctor.check(dynamicSubcommand(app, cmd), appname+" "+cmd)
+	}
+}
+
+// checkUsages warns about the flags of the flagset that have no usage.
+func (doctor *doctor) checkUsages(name string, flagset *FlagSet) {
+	for _, info := range flagset.Targets() {
+		if info.Usage == defaultFlagUsage {
+			doctor.warnings = append(doctor.warnings, fmt.Sprintf("%v: flag -%v has no usage", name, info.Name))
+		}
+	}
+}
+
+// isDoctorCommand returns true if the arguments run the doctor command, and the application does
+// not have a doctor command of its own.
+func isDoctorCommand(app interface{}, arguments []string) bool {
+	if len(arguments) != 1 || arguments[0] != DoctorCommand {
+		return false
+	} else if found, _ := hasCommand(app, DoctorCommand); found {
+		return false
+	}
+	subapp, _, _ := subCommand(app, DoctorCommand)
+	return subapp == nil
+}
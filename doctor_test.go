@@ -0,0 +1,54 @@
+package commander_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/apourchet/commander"
+	"github.com/stretchr/testify/require"
+)
+
+type UnwiredApplication struct {
+	Shared SharedFlags        `commander:"flagstruct"`
+	Other  SharedFlags        `commander:"flagstruct"`
+	Sub    *SubSubApplication `commander:"subcommand=sub,Runs sub commands"`
+	Nil    *SubSubApplication `commander:"subcommand=nil,Never set"`
+	Bare   *SubSubApplication `commander:"subcommand=bare"`
+}
+
+type SharedFlags struct {
+	Region string `commander:"flag=region,Also the region"`
+}
+
+func TestDoctor(t *testing.T) {
+	var buf bytes.Buffer
+	cmd := commander.New()
+	err := cmd.RunDoctor(&buf, &UnwiredApplication{Sub: &SubSubApplication{}, Bare: &SubSubApplication{}})
+	require.Error(t, err)
+	require.Len(t, err.(commander.ValidationError).Problems, 2)
+
+	expected := `error: CLI: failed to get flagset: failed to get flagset for sub-struct: failed to setup flag for application: Duplicate binding of flag: UnwiredApplication.Shared.Region and UnwiredApplication.Other.Region both bind --region
+error: CLI: subcommand nil is nil
+warning: CLI: subcommand bare has no description
+2 errors, 1 warnings
+`
+	require.Equal(t, expected, buf.String())
+
+	buf.Reset()
+	require.NoError(t, cmd.RunDoctor(&buf, &DeprecatingApplication{Sub: &SubSubApplication{}, Old: &SubSubApplication{}}))
+	require.Equal(t, "no problems found\n", buf.String())
+}
+
+func TestDoctorCommand(t *testing.T) {
+	var buf bytes.Buffer
+	cmd := commander.New()
+	cmd.UsageOutput = ioutil.Discard
+	cmd.Stdout = &buf
+	cmd.Doctor = true
+	require.Error(t, cmd.RunCLI(&UnwiredApplication{}, []string{"doctor"}))
+	require.Contains(t, buf.String(), "error: CLI: subcommand sub is nil\n")
+
+	cmd.Doctor = false
+	require.Error(t, cmd.RunCLI(&Application{}, []string{"doctor"}))
+}
@@ -0,0 +1,57 @@
+package commander
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/apourchet/commander/utils"
+)
+
+// dynamicSubcommand returns the application of the subcommand named from the maps of the
+// application that are tagged with a SubcommandsDirective or returned by its SubcommandsProvider
+// implementation, or nil if there is none.
+func dynamicSubcommand(app interface{}, cmd string) interface{} {
+	for _, subcommands := range dynamicSubcommandMaps(app) {
+		value := subcommands.MapIndex(reflect.ValueOf(cmd).Convert(subcommands.Type().Key()))
+		if value.IsValid() && !(value.Kind() == reflect.Interface && value.IsNil()) {
+			return value.Interface()
+		}
+	}
+	return nil
+}
+
+// dynamicSubcommandNames returns the sorted names of the dynamic subcommands of the application.
+func dynamicSubcommandNames(app interface{}) []string {
+	names := []string{}
+	for _, subcommands := range dynamicSubcommandMaps(app) {
+		for _, key := range subcommands.MapKeys() {
+			if !containsString(names, key.String()) {
+				names = append(names, key.String())
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func dynamicSubcommandMaps(app interface{}) []reflect.Value {
+	v, valid := utils.DerefValue(app)
+	if !valid || v.Kind() != reflect.Struct {
+		return nil
+	}
+	maps := []reflect.Value{}
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		alias := strings.SplitN(field.Tag.Get(FieldTag), "=", 2)[0]
+		if alias != SubcommandsDirective || field.Type.Kind() != reflect.Map ||
+			field.Type.Key().Kind() != reflect.String || field.PkgPath != "" {
+			continue
+		}
+		maps = append(maps, v.Field(i))
+	}
+	if provider, ok := app.(SubcommandsProvider); ok {
+		maps = append(maps, reflect.ValueOf(provider.Subcommands()))
+	}
+	return maps
+}
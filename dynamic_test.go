@@ -0,0 +1,62 @@
+package commander_test
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/apourchet/commander"
+	"github.com/stretchr/testify/require"
+)
+
+type ResourceApplication struct {
+	Resources map[string]*SubSubApplication `commander:"subcommands"`
+	Others    map[string]interface{}        `commander:"subcommands"`
+}
+
+func TestDynamicSubcommands(t *testing.T) {
+	cmd := commander.New()
+	cmd.UsageOutput = ioutil.Discard
+	disks, other := &SubSubApplication{}, &SubSubApplication{}
+	app := &ResourceApplication{
+		Resources: map[string]*SubSubApplication{"disks": disks, "volumes": {}},
+		Others:    map[string]interface{}{"other": other},
+	}
+
+	require.NoError(t, cmd.RunCLI(app, []string{"disks", "opdeep"}))
+	require.Equal(t, 1, disks.count)
+	require.NoError(t, cmd.RunCLI(app, []string{"other", "opdeep"}))
+	require.Equal(t, 1, other.count)
+	require.Error(t, cmd.RunCLI(app, []string{"networks", "opdeep"}))
+
+	expected := `Usage of CLI:
+
+Sub-Commands:
+  disks  |  No description for this subcommand
+  other  |  No description for this subcommand
+  volumes  |  No description for this subcommand
+`
+	assertEqualLines(t, expected, cmd.Usage(app))
+	require.NoError(t, commander.Validate(app))
+	require.Error(t, commander.Validate(&struct {
+		Bad []string `commander:"subcommands"`
+	}{}))
+}
+
+type ProvidingApplication struct {
+	features map[string]interface{}
+}
+
+func (app *ProvidingApplication) Subcommands() map[string]interface{} { return app.features }
+
+func TestSubcommandsProvider(t *testing.T) {
+	cmd := commander.New()
+	cmd.UsageOutput = ioutil.Discard
+	beta := &SubSubApplication{}
+	app := &ProvidingApplication{features: map[string]interface{}{}}
+	require.Error(t, cmd.RunCLI(app, []string{"beta", "opdeep"}))
+
+	app.features["beta"] = beta
+	require.NoError(t, cmd.RunCLI(app, []string{"beta", "opdeep"}))
+	require.Equal(t, 1, beta.count)
+	require.Contains(t, cmd.Usage(app), "  beta  |  No description for this subcommand\n")
+}
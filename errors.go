@@ -1,5 +1,13 @@
 package commander
 
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
 type applicationError struct {
 	error
 }
@@ -8,3 +16,128 @@ func isApplicationError(err error) bool {
 	_, ok := err.(applicationError)
 	return ok
 }
+
+// unwrapApplicationError returns the error that the application returned if err comes from the
+// application, and err itself otherwise.
+func unwrapApplicationError(err error) error {
+	if inner, ok := err.(applicationError); ok {
+		return inner.error
+	}
+	return err
+}
+
+// TimeoutError is the error returned by RunCLI when a command does not finish before the timeout
+// of its subcommand, or the one given through the timeout flag.
+type TimeoutError struct {
+	Command string
+	Timeout time.Duration
+}
+
+func (err TimeoutError) Error() string {
+	return fmt.Sprintf("command %v timed out after %v", err.Command, err.Timeout)
+}
+
+// ExitError is the error returned by RunCLI when a command returns a non-zero exit code, along
+// with the error that the command returned, if any.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (err ExitError) Error() string {
+	if err.Err != nil {
+		return err.Err.Error()
+	}
+	return fmt.Sprintf("exit status %v", err.Code)
+}
+
+// ExitCode returns the exit code that a CLI should exit with given the error returned by RunCLI:
+// 0 without an error, the exit code of the command for an ExitError and 1 otherwise.
+//
+//	os.Exit(commander.ExitCode(commander.New().RunCLI(app, os.Args[1:])))
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	} else if exit, ok := errors.Cause(err).(ExitError); ok {
+		return exit.Code
+	}
+	return 1
+}
+
+// UsageError is the error returned by RunCLI when the command line does not match the application:
+// an unknown command or flag, or the wrong number of arguments for a command. Error returns its
+// cause and its hint on one line, and FormatError renders it with the argument at fault underlined.
+type UsageError struct {
+	// Command is the name of the application followed by its subcommands, and Arguments are the
+	// arguments that it was given. Token is the one of those arguments that is at fault, if any.
+	Command   string
+	Arguments []string
+	Token     string
+
+	// Cause tells what is wrong with the command line, and Hint how to fix it.
+	Cause string
+	Hint  string
+}
+
+func (err UsageError) Error() string {
+	if err.Hint == "" {
+		return err.Cause
+	}
+	return err.Cause + "; " + err.Hint
+}
+
+// commandNotFoundError returns the error for arguments that do not start with a command of the
+// application, suggesting the closest commands.
+func commandNotFoundError(app interface{}, appname string, arguments []string, commands []string) UsageError {
+	err := UsageError{
+		Command:   appname,
+		Arguments: arguments,
+		Cause:     fmt.Sprintf("failed to find possible method: %v", commands),
+	}
+	if len(arguments) == 0 {
+		return err
+	}
+	err.Token = arguments[0]
+	if suggestions := suggestCommands(arguments[0], commandCandidates(app)); len(suggestions) > 0 {
+		err.Hint = fmt.Sprintf("did you mean %v?", strings.Join(suggestions, ", "))
+	}
+	return err
+}
+
+// arityError returns the error for a command that was not given the number of arguments it
+// requires. The Commander fills in the command line and the synopsis of the command once the error
+// reaches it.
+func arityError(cmd string, required int, args []string) UsageError {
+	err := UsageError{
+		Token: cmd,
+		Cause: fmt.Sprintf("command requires %v arguments, have %v", required, len(args)),
+	}
+	if len(args) > required {
+		err.Token = args[required]
+	}
+	return err
+}
+
+// argumentCountError returns the error for a command that was given a number of arguments out of
+// the range set by the ArgumentCountProvider of the application.
+func argumentCountError(cmd string, min int, max int, args []string) UsageError {
+	var expected string
+	switch {
+	case max < 0:
+		expected = fmt.Sprintf("at least %v", min)
+	case min == max:
+		expected = fmt.Sprintf("exactly %v", min)
+	case min <= 0:
+		expected = fmt.Sprintf("at most %v", max)
+	default:
+		expected = fmt.Sprintf("between %v and %v", min, max)
+	}
+	err := UsageError{
+		Token: cmd,
+		Cause: fmt.Sprintf("command %v takes %v arguments, have %v", cmd, expected, len(args)),
+	}
+	if max >= 0 && len(args) > max {
+		err.Token = args[max]
+	}
+	return err
+}
@@ -0,0 +1,109 @@
+package commander
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/apourchet/commander/utils"
+)
+
+// FindCommandName is the command that the Commander adds to the application when Find is set.
+const FindCommandName = "find"
+
+// FindCommand searches the whole tree of subcommands of the application for the commands and
+// subcommands whose name or description contains the keyword given, regardless of case. It returns
+// their full paths, starting with the name of the application, like "myapp subapp opfour". The
+// commands of each application come first, followed by its subcommands in the order they are
+// declared. Subcommands that are nil are left out.
+func (commander Commander) FindCommand(app interface{}, keyword string) []string {
+	finder := &commandFinder{keyword: strings.ToLower(keyword), visited: map[interface{}]bool{}}
+	finder.search(app, getCLIName(app))
+	return finder.paths
+}
+
+type commandFinder struct {
+	keyword string
+	visited map[interface{}]bool
+	paths   []string
+}
+
+// search adds the paths of the matching commands and subcommands of the application, named
+// appname, and searches its subcommands in turn.
+func (finder *commandFinder) search(app interface{}, appname string) {
+	if reflect.ValueOf(app).Kind() == reflect.Ptr {
+		if finder.visited[app] {
+			return
+		}
+		finder.visited[app] = true
+	}
+
+	for _, cmd := range commandNames(app) {
+		finder.match(appname+" "+cmd, cmd, commandDescription(app, cmd, ""))
+	}
+
+	st, valid := utils.DerefType(app)
+	if !valid {
+		return
+	}
+	for i := 0; i < st.NumField(); i++ {
+		split := strings.SplitN(st.Field(i).Tag.Get(FieldTag), "=", 2)
+		if len(split) != 2 || split[0] != SubcommandDirective {
+			continue
+		}
+		directive, _ := parseSubcommandDirective(split[1])
+		if directive.renamed != "" {
+			continue
+		}
+		subapp, _, _ := subCommand(app, directive.cmd)
+		if v := reflect.ValueOf(subapp); subapp != nil && (v.Kind() != reflect.Ptr || !v.IsNil()) {
+			finder.match(appname+" "+directive.cmd, directive.cmd, commandDescription(app, directive.cmd, directive.description))
+			finder.search(subapp, appname+" "+directive.cmd)
+		}
+	}
+	for _, cmd := range dynamicSubcommandNames(app) {
+		finder.match(appname+" "+cmd, cmd, commandDescription(app, cmd, ""))
+		finder.search(dynamicSubcommand(app, cmd), appname+" "+cmd)
+	}
+}
+
+func (finder *commandFinder) match(path string, name string, description string) {
+	if strings.Contains(strings.ToLower(name), finder.keyword) || strings.Contains(strings.ToLower(description), finder.keyword) {
+		finder.paths = append(finder.paths, path)
+	}
+}
+
+// commandDescription returns the description that the application gives to the command, or the
+// default description given if it has none.
+func commandDescription(app interface{}, cmd string, def string) string {
+	if provider, ok := app.(CommandDescriptionProvider); ok {
+		if desc := provider.GetCommandDescription(cmd); desc != "" {
+			return desc
+		}
+	}
+	return def
+}
+
+// runFind prints the full paths of the commands of the application that match the keyword.
+func (commander Commander) runFind(app interface{}, keyword string) error {
+	paths := commander.FindCommand(app, keyword)
+	if len(paths) == 0 {
+		return fmt.Errorf("no command matches %q", keyword)
+	}
+	for _, path := range paths {
+		fmt.Fprintln(commander.Stdout, path)
+	}
+	return nil
+}
+
+// isFindCommand returns true if the arguments run the find command, and the application does not
+// have a find command of its own.
+func isFindCommand(app interface{}, arguments []string) bool {
+	if len(arguments) != 2 || arguments[0] != FindCommandName {
+		return false
+	} else if found, _ := hasCommand(app, FindCommandName); found {
+		return false
+	}
+	subapp, _, _ := subCommand(app, FindCommandName)
+	return subapp == nil
+}
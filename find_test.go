@@ -0,0 +1,36 @@
+package commander_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/apourchet/commander"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindCommand(t *testing.T) {
+	app := &Application{SubApp: &SubApplication{SubSubApp: &SubSubApplication{}}, SubApp2: &SubApplication{}}
+	cmd := commander.New()
+	require.Equal(t, []string{"myapp opthree", "myapp subapp opthree", "myapp subapp2 opthree"}, cmd.FindCommand(app, "Three"))
+	require.Equal(t, []string{"myapp subapp subsubapp"}, cmd.FindCommand(app, "subsub"))
+	require.Equal(t, []string{"myapp subapp subsubapp opdeep"}, cmd.FindCommand(app, "deep"))
+	require.Empty(t, cmd.FindCommand(app, "nothing"))
+
+	require.Equal(t, []string{"CLI cmd1"}, cmd.FindCommand(&Application3{}, "runs"))
+	require.Equal(t, []string{"CLI ctx"}, cmd.FindCommand(&TimeoutApplication{Slow: &SlowApplication{}, Ctx: &ContextApplication{}}, "context"))
+}
+
+func TestFindBuiltinCommand(t *testing.T) {
+	var out bytes.Buffer
+	cmd := commander.New()
+	cmd.Stdout = &out
+	cmd.Find = true
+
+	app := &Application{SubApp: &SubApplication{}}
+	require.NoError(t, cmd.RunCLI(app, []string{"find", "four"}))
+	require.Equal(t, "myapp subapp opfour\n", out.String())
+	require.EqualError(t, cmd.RunCLI(app, []string{"find", "nothing"}), `no command matches "nothing"`)
+
+	cmd.Find = false
+	require.Error(t, cmd.RunCLI(app, []string{"find", "four"}))
+}
@@ -3,8 +3,16 @@ package commander
 import (
 	"flag"
 	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
 	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/apourchet/commander/utils"
 	"github.com/pkg/errors"
@@ -17,6 +25,54 @@ type flagTarget struct {
 	object interface{}
 	field  reflect.StructField
 	usage  string
+
+	// shared holds the other targets bound to the same flag name when the FlagSet allows
+	// shared flags. Setting the flag sets all of them.
+	shared []*flagTarget
+
+	// changed is true once the flag has been set, even if it was set to its default value.
+	changed bool
+
+	// depth is how deeply nested in flagstructs the field is, 0 being the application itself.
+	depth int
+
+	// path is the path of the field from the application, like Application.B.B1.
+	path string
+
+	// env is the environment variable that sets the flag when it is not given, and required makes
+	// parsing fail when the flag is neither given nor set by its environment variable.
+	env      string
+	required bool
+
+	// requiredIf lists the conditions on other flags of the set under which the flag is required.
+	requiredIf []flagCondition
+
+	// fromEnv is true once the flag has been set by its environment variable, and secret keeps its
+	// value out of the configuration printed by the Commander.
+	fromEnv bool
+	secret  bool
+
+	// min and max bound the values of numeric flags. They are kept as written in the directive.
+	min, max string
+
+	// placeholder stands for the value of the flag in the usage, like FILE in "-output FILE".
+	placeholder string
+
+	// index is the position of the flag in the declarations of the set, and order its position in
+	// the usage if it is not listed in the default order.
+	index, order int
+
+	// pattern is the regular expression that the values of string flags need to match, and minlen
+	// and maxlen bound their lengths in characters when they are positive.
+	pattern        *regexp.Regexp
+	minlen, maxlen int
+
+	// group is the heading that the flag is listed under in the usage, which defaults to the name
+	// of the field of the flagstruct or flag slice that holds the flag.
+	group string
+
+	// def is the default value of the flag, as written in its directive.
+	def string
 }
 
 // newFlagTarget creates a new FlagTarget that points to the object given.
@@ -34,22 +90,230 @@ func (target *flagTarget) Usage() string {
 	if target.field.Type.Kind() == reflect.String {
 		def = fmt.Sprintf(`"%s"`, def)
 	}
-	return fmt.Sprintf(`%s (type: %s, default: %s)`, target.usage, target.field.Type.Kind(), def)
+	kind := target.field.Type.Kind().String()
+	if target.field.Type == reflect.TypeOf(time.Duration(0)) {
+		kind = "duration"
+	} else if t := target.field.Type; t.PkgPath() == "math/big" || (t.Kind() == reflect.Ptr && t.Elem().PkgPath() == "math/big") {
+		kind = strings.TrimPrefix(t.String(), "*")
+	}
+	constraints := ""
+	if target.min != "" {
+		constraints += ", min: " + target.min
+	}
+	if target.max != "" {
+		constraints += ", max: " + target.max
+	}
+	if target.pattern != nil {
+		constraints += ", pattern: " + target.pattern.String()
+	}
+	if target.minlen > 0 {
+		constraints += fmt.Sprintf(", minlen: %d", target.minlen)
+	}
+	if target.maxlen > 0 {
+		constraints += fmt.Sprintf(", maxlen: %d", target.maxlen)
+	}
+	// The empty back-quoted name keeps the flag package from printing a "value" placeholder
+	// after the name of the flag. PrintDefaults prints the placeholder of the flag instead, if it
+	// has one, which then stands for its type.
+	if target.placeholder != "" {
+		return fmt.Sprintf("``"+`%s (default: %s%s)`, target.usage, def, constraints)
+	}
+	return fmt.Sprintf("``"+`%s (type: %s, default: %s%s)`, target.usage, kind, def, constraints)
 }
 
 // String has to be implemented for flag.Value.
 func (target *flagTarget) String() string { return "" }
 
-// IsBoolFlag returns false always so that the flag usage does not show "value" after each flag.
+// IsBoolFlag returns true for boolean fields so that they can be set without a value.
 func (target *flagTarget) IsBoolFlag() bool {
 	return target.field.Type.Kind() == reflect.Bool
 }
 
-// Set sets the value of the field that the FlagTarget is bound to.
+// Set sets the value of the field that the FlagTarget is bound to. Map fields can also be set one
+// key=value definition at a time, the flag being repeated for each definition.
 func (target *flagTarget) Set(value string) error {
-	if err := utils.SetField(target.object, target.field.Name, value); err != nil {
+	if err := target.checkRange(value); err != nil {
+		return err
+	} else if err := target.checkText(value); err != nil {
 		return err
 	}
+	if target.field.Type.Kind() == reflect.Map && isDefinition(value) {
+		if err := target.define(value); err != nil {
+			return err
+		}
+	} else if err := utils.SetField(target.object, target.field.Name, value); err != nil {
+		return err
+	}
+	target.changed = true
+	for _, other := range target.shared {
+		if err := other.Set(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flagCondition is a condition on the value of a flag: that it was set, or that it has a value.
+type flagCondition struct {
+	name     string
+	value    string
+	hasValue bool
+}
+
+func (condition flagCondition) String() string {
+	if condition.hasValue {
+		return fmt.Sprintf("-%v is %v", condition.name, condition.value)
+	}
+	return fmt.Sprintf("-%v is set", condition.name)
+}
+
+// met returns true if the flag of the condition was set, or has the value of the condition once
+// both are parsed to the type of the flag.
+func (condition flagCondition) met(set *FlagSet) (bool, error) {
+	target, found := set.targets[condition.name]
+	if alias, isAlias := set.aliases[condition.name]; !found && isAlias {
+		target, found = set.targets[alias.name], true
+	}
+	if !found {
+		return false, errors.Errorf("Flag -%v in a requiredif option is not defined", condition.name)
+	} else if !condition.hasValue {
+		return target.changed, nil
+	}
+	expected, err := utils.ParseString(target.field.Type, condition.value)
+	if err != nil {
+		return false, errors.Wrapf(err, "Invalid value %q in a requiredif option on flag -%v", condition.value, condition.name)
+	}
+	str, _ := utils.StringifyValue(expected)
+	return str == target.value(), nil
+}
+
+// source returns where the value of the flag comes from, as described by FlagInfo.
+func (target *flagTarget) source() string {
+	if target.fromEnv {
+		return "env"
+	} else if target.changed {
+		return "flag"
+	}
+	return "default"
+}
+
+// checkRange returns an error if the value is out of the bounds of the flag. Values that do not
+// parse are left for the field to reject.
+func (target *flagTarget) checkRange(value string) error {
+	if target.min == "" && target.max == "" {
+		return nil
+	}
+	parsed, err := utils.ParseString(target.field.Type, value)
+	if err != nil {
+		return nil
+	}
+	min, _ := utils.ParseString(target.field.Type, target.min)
+	max, _ := utils.ParseString(target.field.Type, target.max)
+	switch {
+	case target.min != "" && target.max != "" && (compareNumbers(parsed, min) < 0 || compareNumbers(parsed, max) > 0):
+		return errors.Errorf("must be between %v and %v", target.min, target.max)
+	case target.min != "" && compareNumbers(parsed, min) < 0:
+		return errors.Errorf("must be at least %v", target.min)
+	case target.max != "" && compareNumbers(parsed, max) > 0:
+		return errors.Errorf("must be at most %v", target.max)
+	}
+	return nil
+}
+
+// checkText returns an error if the value of a string flag does not match its pattern or is not
+// within its length bounds.
+func (target *flagTarget) checkText(value string) error {
+	length := utf8.RuneCountInString(value)
+	switch {
+	case target.pattern != nil && !target.pattern.MatchString(value):
+		return errors.Errorf("must match %v", target.pattern)
+	case target.minlen > 0 && length < target.minlen:
+		return errors.Errorf("must be at least %d characters long", target.minlen)
+	case target.maxlen > 0 && length > target.maxlen:
+		return errors.Errorf("must be at most %d characters long", target.maxlen)
+	}
+	return nil
+}
+
+// compareNumbers returns -1, 0 or 1 depending on whether the first number is lower than, equal to
+// or greater than the second one. Both need to be of the same numeric type.
+func compareNumbers(a, b reflect.Value) int {
+	var less, greater bool
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		less, greater = a.Int() < b.Int(), a.Int() > b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		less, greater = a.Uint() < b.Uint(), a.Uint() > b.Uint()
+	case reflect.Float32, reflect.Float64:
+		less, greater = a.Float() < b.Float(), a.Float() > b.Float()
+	}
+	if less {
+		return -1
+	} else if greater {
+		return 1
+	}
+	return 0
+}
+
+// isNumeric returns true if the values of the type are numbers.
+func isNumeric(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// define adds the key=value definition to the map field of the target, or each of the definitions
+// if they are bare comma-separated, like "a=1,b=2". The first definition replaces the default value
+// of the field.
+func (target *flagTarget) define(definition string) error {
+	pairs, err := utils.ParseString(reflect.TypeOf(map[string]string{}), definition)
+	if err != nil {
+		return errors.Errorf("Expected a key=value definition, got %q", definition)
+	}
+	field, err := target.fieldValue()
+	if err != nil {
+		return err
+	}
+
+	defined := reflect.MakeMap(field.Type())
+	for name, value := range pairs.Interface().(map[string]string) {
+		key, err := utils.ParseString(field.Type().Key(), name)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to parse key of definition %q", definition)
+		}
+		elem, err := utils.ParseString(field.Type().Elem(), value)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to parse value of definition %q", definition)
+		}
+		defined.SetMapIndex(key, elem)
+	}
+
+	if !target.changed || field.IsNil() {
+		field.Set(reflect.MakeMap(field.Type()))
+	}
+	for _, key := range defined.MapKeys() {
+		field.SetMapIndex(key, defined.MapIndex(key))
+	}
+	return nil
+}
+
+// isDefinition returns true if the value of a map flag is a single key=value definition rather
+// than a whole map in JSON or YAML.
+func isDefinition(value string) bool {
+	return !strings.HasPrefix(strings.TrimSpace(value), "{") && strings.Contains(value, "=") && !strings.Contains(value, "\n")
+}
+
+// share binds another target to the same flag as this one. Both targets need to have the same type
+// since they will be set from the same value.
+func (target *flagTarget) share(other *flagTarget) error {
+	if other.field.Type != target.field.Type {
+		return errors.Errorf("Conflicting types for shared flag: %v and %v", target.field.Type, other.field.Type)
+	}
+	target.shared = append(target.shared, other)
 	return nil
 }
 
@@ -58,26 +322,352 @@ func (target *flagTarget) value() string {
 	return val
 }
 
+// fieldValue returns the field that the target is bound to.
+func (target *flagTarget) fieldValue() (reflect.Value, error) {
+	v, valid := utils.DerefValue(target.object)
+	if !valid || v.Kind() != reflect.Struct {
+		return reflect.Value{}, errors.Errorf("Flag %v is bound to a nil struct", target.field.Name)
+	}
+	return v.FieldByName(target.field.Name), nil
+}
+
 // FlagSet is the wrapper around flag.FlagSet that allows setting of a flag multiple times. This is
 // useful in the case of subcommands that might use the same flag.
 type FlagSet struct {
 	*flag.FlagSet
 	targets map[string]*flagTarget
+
+	// shared allows the same flag name to be bound to several fields, and shadow lets the fields of
+	// nested flagstructs take over the flag names of the structs that hold them.
+	shared bool
+	shadow bool
+
+	// depth is the nesting depth of the struct whose flags are currently being set up.
+	depth int
+
+	// path is the field path of the struct whose flags are currently being set up, and command the
+	// command whose flagstruct is being set up, if any. Both are used to describe duplicate flags.
+	path    []string
+	command string
+
+	// aliases holds the old names of the flags, and warnAliases makes them print a deprecation
+	// warning when they are used.
+	aliases     map[string]*flagAlias
+	warnAliases bool
+
+	// structs holds the application and all the flagstructs and flag slice elements whose flags
+	// were set up on this set, and nesting the ones whose flags are being set up, from the
+	// application down to the current flagstruct.
+	structs []interface{}
+	nesting []nestedStruct
+
+	// description is the long description of the command of the set, shown in its usage.
+	description string
+
+	// declarationOrder lists the flags in the usage in the order they were declared in rather than
+	// by name, and declared counts the flags declared so far.
+	declarationOrder bool
+	declared         int
+
+	// envs holds the targets of the fields that are only set from their environment variable,
+	// through an EnvDirective.
+	envs []*flagTarget
+
+	// errorHandling is how Parse handles errors, the embedded flag.FlagSet continuing on errors.
+	errorHandling flag.ErrorHandling
+
+	// keepValues leaves the fields as they are rather than setting the flags that are still zero to
+	// their default value, so that the current values of an application can be read.
+	keepValues bool
 }
 
 // NewFlagSet returns a new FlagSet, with the internal variables initialized.
 func newFlagSet(flagset *flag.FlagSet) *FlagSet {
-	return &FlagSet{
-		FlagSet: flagset,
-		targets: map[string]*flagTarget{},
+	set := &FlagSet{
+		FlagSet:       flagset,
+		targets:       map[string]*flagTarget{},
+		aliases:       map[string]*flagAlias{},
+		errorHandling: flagset.ErrorHandling(),
+	}
+	set.FlagSet.Init(flagset.Name(), flag.ContinueOnError)
+	set.FlagSet.Usage = set.usage
+	return set
+}
+
+// Bind registers the flags of the tagged fields of the application on a flag.FlagSet that the caller
+// owns, for projects that parse their flags themselves rather than through RunCLI. The flagstructs
+// and flag slices of the application are bound too, but not its subcommands. Parsing the flagset
+// sets the fields; the environment variables, required and requiredif options of the flags are
+// only enforced by the Parse method of FlagSet, and are left out. Bind fails without changing the
+// flagset if one of the flags is already defined on it.
+func Bind(fs *flag.FlagSet, app interface{}) error {
+	set := &FlagSet{
+		FlagSet:       fs,
+		targets:       map[string]*flagTarget{},
+		aliases:       map[string]*flagAlias{},
+		errorHandling: fs.ErrorHandling(),
+		path:          []string{typeName(app)},
+	}
+	if err := setupFlagSet(app, set); err != nil {
+		return errors.Wrap(err, "failed to bind flags")
+	}
+	for _, name := range append(set.targetNames(), set.aliasNames()...) {
+		if fs.Lookup(name) != nil {
+			return errors.Errorf("failed to bind flags: flag -%v is already defined", name)
+		}
+	}
+	set.finish()
+	return nil
+}
+
+// usage prints the usage of the set like the default usage of the flag package does, with the
+// long description of its command and the flags in the order of PrintDefaults.
+func (set *FlagSet) usage() {
+	if set.Name() == "" {
+		fmt.Fprintf(set.Output(), "Usage:\n")
+	} else {
+		fmt.Fprintf(set.Output(), "Usage of %s:\n", set.Name())
+	}
+	if strings.TrimSpace(set.description) != "" {
+		fmt.Fprintf(set.Output(), "\n%v\n", wrapText(set.description, "  ", usageWidth))
+		if len(set.targets) > 0 {
+			fmt.Fprintln(set.Output())
+		}
+	}
+	set.PrintDefaults()
+}
+
+// PrintDefaults prints the usage of the flags of the set like flag.FlagSet.PrintDefaults does. The
+// flags with an order option come first, and the others follow by name, or in declaration order if
+// the set was made by a Commander with DeclarationOrder. Aliases follow the flag they stand for.
+// When the flags come from several groups, the flags of the application come first and those of
+// each group follow under its "<group> options:" heading, in the order the groups are declared.
+func (set *FlagSet) PrintDefaults() {
+	names := []string{}
+	set.VisitAll(func(f *flag.Flag) { names = append(names, f.Name) })
+	order, declared := map[string]int{}, map[string]int{}
+	for name, target := range set.targets {
+		order[name], declared[name] = target.order, 2*target.index
+	}
+	for alias, target := range set.aliases {
+		if _, found := set.targets[alias]; !found {
+			order[alias], declared[alias] = order[target.name], declared[target.name]+1
+		}
+	}
+	orderNames(names, order, declared, set.declarationOrder)
+
+	groups, grouped, first := []string{}, map[string][]string{}, map[string]int{}
+	for _, name := range names {
+		target, found := set.targets[name]
+		if alias, isAlias := set.aliases[name]; !found && isAlias {
+			target, found = set.targets[alias.name], true
+		}
+		group := ""
+		if found {
+			group = target.group
+		}
+		if _, seen := grouped[group]; !seen {
+			groups, first[group] = append(groups, group), math.MaxInt
+		}
+		grouped[group] = append(grouped[group], name)
+		if found && target.index < first[group] {
+			first[group] = target.index
+		}
+	}
+	if len(groups) < 2 {
+		set.printFlags(names)
+		return
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i] == "" || (groups[j] != "" && first[groups[i]] < first[groups[j]])
+	})
+	for i, group := range groups {
+		if group != "" {
+			if i > 0 {
+				fmt.Fprintln(set.Output())
+			}
+			fmt.Fprintf(set.Output(), "%v options:\n", group)
+		}
+		set.printFlags(grouped[group])
+	}
+}
+
+// printFlags prints the usage of the flags given, in order.
+func (set *FlagSet) printFlags(names []string) {
+	for _, name := range names {
+		f := set.Lookup(name)
+		if target, found := set.targets[name]; found && target.placeholder != "" {
+			_, usage := flag.UnquoteUsage(f)
+			fmt.Fprintf(set.Output(), "  -%s %s\n    \t%s\n", name, target.placeholder, strings.ReplaceAll(usage, "\n", "\n    \t"))
+			continue
+		}
+		single := flag.NewFlagSet(set.Name(), flag.ContinueOnError)
+		single.SetOutput(set.Output())
+		single.Var(f.Value, f.Name, f.Usage)
+		single.Lookup(f.Name).DefValue = f.DefValue
+		single.PrintDefaults()
 	}
 }
 
 // Stringify returns the stringified version of the flagset.
 func (set *FlagSet) Stringify() []string {
+	return set.stringify(false)
+}
+
+// StringifyChanged returns the stringified version of the flags that were set while parsing,
+// leaving out the ones that still have their default value.
+func (set *FlagSet) StringifyChanged() []string {
+	return set.stringify(true)
+}
+
+// MergeStrategy decides what FlagSet.Merge does with the flags that are defined in both sets.
+type MergeStrategy int
+
+const (
+	// MergeError makes Merge fail without changing the set.
+	MergeError MergeStrategy = iota
+	// MergeSkip keeps the flag of the set that is merged into.
+	MergeSkip
+	// MergeOverride replaces the flag with the one of the other set.
+	MergeOverride
+)
+
+// Merge adds the flags of the other set to this one, resolving the flags defined in both sets with
+// the strategy given. Once merged, parsing this set populates the applications of both sets.
+func (set *FlagSet) Merge(other *FlagSet, strategy MergeStrategy) error {
+	for name, target := range other.targets {
+		if existing, found := set.targets[name]; found && strategy != MergeSkip && strategy != MergeOverride {
+			return set.duplicateError(name, existing.path, target.path)
+		}
+	}
+
+	for name, target := range other.targets {
+		if _, found := set.targets[name]; found && strategy == MergeSkip {
+			continue
+		}
+		set.targets[name] = target
+		if f := set.Lookup(name); f != nil {
+			f.Value, f.Usage, f.DefValue = target, target.Usage(), target.String()
+		} else {
+			set.Var(target, name, target.Usage())
+		}
+	}
+	for alias, target := range other.aliases {
+		if _, found := set.aliases[alias]; !found && set.Lookup(alias) == nil {
+			set.aliases[alias] = &flagAlias{set: set, name: target.name, alias: alias}
+			set.Var(set.aliases[alias], alias, "``Deprecated alias of -"+target.name)
+		}
+	}
+	set.envs = append(set.envs, other.envs...)
+	return nil
+}
+
+// FlagInfo describes a flag that is registered on a FlagSet. Source tells where its value comes
+// from: "flag" if it was given on the command line, "env" if it was set by its environment
+// variable and "default" otherwise.
+type FlagInfo struct {
+	Name    string
+	Usage   string
+	Type    reflect.Type
+	Value   string
+	Changed bool
+	Source  string
+}
+
+// Targets returns the description of all the flags registered on the set, sorted by name.
+func (set *FlagSet) Targets() []FlagInfo {
+	names := []string{}
+	for name := range set.targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	infos := make([]FlagInfo, len(names))
+	for i, name := range names {
+		target := set.targets[name]
+		infos[i] = FlagInfo{
+			Name:    name,
+			Usage:   target.usage,
+			Type:    target.field.Type,
+			Value:   target.value(),
+			Changed: target.changed,
+			Source:  target.source(),
+		}
+	}
+	return infos
+}
+
+// Changed returns true if the flag was set while parsing.
+func (set *FlagSet) Changed(name string) bool {
+	target, found := set.targets[name]
+	return found && target.changed
+}
+
+// GetString returns the value of a string flag.
+func (set *FlagSet) GetString(name string) (string, error) {
+	v, err := set.lookupValue(name, reflect.String)
+	if err != nil {
+		return "", err
+	}
+	return v.String(), nil
+}
+
+// GetInt returns the value of an int flag.
+func (set *FlagSet) GetInt(name string) (int, error) {
+	v, err := set.lookupValue(name, reflect.Int)
+	if err != nil {
+		return 0, err
+	}
+	return int(v.Int()), nil
+}
+
+// GetBool returns the value of a bool flag.
+func (set *FlagSet) GetBool(name string) (bool, error) {
+	v, err := set.lookupValue(name, reflect.Bool)
+	if err != nil {
+		return false, err
+	}
+	return v.Bool(), nil
+}
+
+// GetDuration returns the value of a time.Duration flag.
+func (set *FlagSet) GetDuration(name string) (time.Duration, error) {
+	v, err := set.lookupValue(name, reflect.Int64)
+	if err != nil {
+		return 0, err
+	} else if v.Type() != reflect.TypeOf(time.Duration(0)) {
+		return 0, errors.Errorf("Flag %v is of type %v, not a duration", name, v.Type())
+	}
+	return time.Duration(v.Int()), nil
+}
+
+// lookupValue returns the field bound to the flag, making sure that it is of the kind given.
+func (set *FlagSet) lookupValue(name string, kind reflect.Kind) (reflect.Value, error) {
+	target, found := set.targets[name]
+	if !found {
+		return reflect.Value{}, errors.Errorf("Flag not found: %v", name)
+	}
+	v, err := target.fieldValue()
+	if err != nil {
+		return v, err
+	} else if v.Kind() != kind {
+		return v, errors.Errorf("Flag %v is of type %v, not %v", name, v.Type(), kind)
+	}
+	return v, nil
+}
+
+// StringifyShell returns the stringified version of the flagset as a single string, quoted so that
+// it can be pasted into a shell.
+func (set *FlagSet) StringifyShell() string {
+	return ShellJoin(set.Stringify())
+}
+
+func (set *FlagSet) stringify(onlyChanged bool) []string {
 	out := []string{}
 	for name, target := range set.targets {
-		if target.IsBoolFlag() {
+		if onlyChanged && !target.changed {
+			continue
+		} else if target.IsBoolFlag() {
 			if target.value() == "true" {
 				out = append(out, "--"+name)
 			}
@@ -88,10 +678,222 @@ func (set *FlagSet) Stringify() []string {
 	return out
 }
 
+// targetNames returns the sorted names of the flags of the set.
+func (set *FlagSet) targetNames() []string {
+	names := []string{}
+	for name := range set.targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// aliasNames returns the sorted aliases of the flags of the set.
+func (set *FlagSet) aliasNames() []string {
+	names := []string{}
+	for name := range set.aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// values returns the current values of the flags of the set, by name.
+func (set *FlagSet) values() map[string]string {
+	values := map[string]string{}
+	for name, target := range set.targets {
+		values[name] = target.value()
+	}
+	return values
+}
+
+// DiffArgs returns the flag arguments that turn the flags of the base application into the flags
+// of the modified one, sorted by flag name. Only the flags whose values differ are returned. DiffArgs
+// fails if the flags of either application cannot be read.
+func DiffArgs(base, modified interface{}) ([]string, error) {
+	commander := New()
+	commander.UsageOutput = ioutil.Discard
+	commander.keepFlagValues = true
+	baseset, err := commander.GetFlagSet(base, "")
+	if err != nil {
+		return nil, err
+	}
+	modifiedset, err := commander.GetFlagSet(modified, "")
+	if err != nil {
+		return nil, err
+	}
+
+	values := baseset.values()
+	out := []string{}
+	for _, info := range modifiedset.Targets() {
+		if value, found := values[info.Name]; found && value == info.Value {
+			continue
+		} else if info.Type.Kind() == reflect.Bool && info.Value == "true" {
+			out = append(out, "--"+info.Name)
+		} else if info.Type.Kind() == reflect.Bool {
+			out = append(out, "--"+info.Name+"="+info.Value)
+		} else {
+			out = append(out, "--"+info.Name, info.Value)
+		}
+	}
+	return out, nil
+}
+
 // SetFlag creates a flag on the flagset given so that when the flagset.
-func (set *FlagSet) setFlag(obj interface{}, field reflect.StructField, directive string) error {
-	name, usage := parseFlagDirective(directive)
-	return set.addTarget(name, obj, field, usage)
+func (set *FlagSet) setFlag(obj interface{}, field reflect.StructField, options flagOptions) error {
+	if err := set.addTarget(options.name, obj, field, options.usage); err != nil {
+		return err
+	}
+	for _, bound := range []string{options.min, options.max} {
+		if bound == "" {
+			continue
+		} else if !isNumeric(field.Type) {
+			return errors.Errorf("Range option on flag %v of non-numeric type %v", options.name, field.Type)
+		} else if _, err := utils.ParseString(field.Type, bound); err != nil {
+			return errors.Wrapf(err, "Invalid bound %q for flag %v", bound, options.name)
+		}
+	}
+	var pattern *regexp.Regexp
+	if (options.pattern != "" || options.minlen > 0 || options.maxlen > 0) && field.Type.Kind() != reflect.String {
+		return errors.Errorf("Text option on flag %v of non-string type %v", options.name, field.Type)
+	} else if options.pattern != "" {
+		var err error
+		if pattern, err = regexp.Compile(options.pattern); err != nil {
+			return errors.Wrapf(err, "Invalid pattern for flag %v", options.name)
+		}
+	}
+	if target := set.targets[options.name]; target.object == obj && target.field.Name == field.Name {
+		target.env, target.required, target.secret = options.env, options.required, options.secret
+		target.requiredIf = options.requiredIf
+		target.min, target.max = options.min, options.max
+		target.pattern, target.minlen, target.maxlen = pattern, options.minlen, options.maxlen
+		target.order, target.placeholder, target.def = options.order, options.placeholder, options.def
+		if options.group != "" {
+			target.group = options.group
+		}
+	}
+	if v, valid := utils.DerefValue(obj); valid && !set.keepValues && options.def != "" && v.FieldByName(field.Name).IsZero() {
+		if err := utils.SetField(obj, field.Name, options.def); err != nil {
+			return errors.Wrapf(err, "Invalid default value for flag %v", options.name)
+		}
+	}
+
+	if options.short != "" {
+		if err := set.addAlias(options.short, options.name); err != nil {
+			return err
+		}
+		set.aliases[options.short].short = true
+	}
+	for _, alias := range options.aliases {
+		if err := set.addAlias(alias, options.name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setEnv binds the field of the object to the environment variable of the env directive.
+func (set *FlagSet) setEnv(obj interface{}, field reflect.StructField, directive string) {
+	variable, usage := cutFirstDirectivePart(directive)
+	target := newFlagTarget(obj, field, usage)
+	target.env = variable
+	set.envs = append(set.envs, target)
+}
+
+// Parse parses the arguments like flag.FlagSet.Parse does. The flags that were not given are then
+// set from their environment variable, if they have one, and the required flags that are still
+// missing make Parse fail. Errors are handled according to the ErrorHandling of the set.
+func (set *FlagSet) Parse(arguments []string) error {
+	return set.handleError(set.parse(arguments))
+}
+
+// ErrorHandling returns the error handling behavior of the set.
+func (set *FlagSet) ErrorHandling() flag.ErrorHandling {
+	return set.errorHandling
+}
+
+// handleError applies the error handling of the set to an error from parsing, once it has been
+// reported: the error is returned with ContinueOnError, the process exits with ExitOnError and
+// the error is panicked with PanicOnError. The embedded flag.FlagSet always continues on errors so
+// that the set can report them before any of that happens.
+func (set *FlagSet) handleError(err error) error {
+	if err == nil {
+		return nil
+	}
+	switch set.errorHandling {
+	case flag.ExitOnError:
+		if errors.Cause(err) == flag.ErrHelp {
+			os.Exit(0)
+		}
+		os.Exit(2)
+	case flag.PanicOnError:
+		panic(err)
+	}
+	return err
+}
+
+// parse parses the arguments into the set, like Parse but regardless of its error handling.
+func (set *FlagSet) parse(arguments []string) error {
+	if err := set.FlagSet.Parse(arguments); err != nil {
+		return err
+	}
+
+	missing := []string{}
+	for _, name := range set.targetNames() {
+		target := set.targets[name]
+		if value, found := os.LookupEnv(target.env); target.env != "" && found && !target.changed {
+			if err := target.Set(value); err != nil {
+				err = errors.Wrapf(err, "Invalid value %q for flag -%v from %v", value, name, target.env)
+				fmt.Fprintln(set.Output(), err)
+				return err
+			}
+			target.fromEnv = true
+		}
+		if target.required && !target.changed {
+			missing = append(missing, "-"+name)
+		}
+	}
+	for _, name := range set.targetNames() {
+		target := set.targets[name]
+		if target.changed {
+			continue
+		}
+		for _, condition := range target.requiredIf {
+			if met, err := condition.met(set); err != nil {
+				fmt.Fprintln(set.Output(), err)
+				return err
+			} else if met {
+				missing = append(missing, fmt.Sprintf("-%v (required when %v)", name, condition))
+				break
+			}
+		}
+	}
+	for _, target := range set.envs {
+		if value, found := os.LookupEnv(target.env); found && target.env != "" {
+			if err := target.Set(value); err != nil {
+				err = errors.Wrapf(err, "Invalid value %q for %v", value, target.env)
+				fmt.Fprintln(set.Output(), err)
+				return err
+			}
+		}
+	}
+	if len(missing) > 0 {
+		err := errors.Errorf("Missing required flags: %v", strings.Join(missing, ", "))
+		fmt.Fprintln(set.Output(), err)
+		return err
+	}
+	return nil
+}
+
+// addAlias makes the alias set the flag named, as if it was that flag.
+func (set *FlagSet) addAlias(alias string, name string) error {
+	if _, found := set.targets[alias]; found {
+		return set.duplicateError(alias, set.targets[alias].path, set.targets[name].path)
+	} else if other, found := set.aliases[alias]; found {
+		return set.duplicateError(alias, set.targets[other.name].path, set.targets[name].path)
+	}
+	set.aliases[alias] = &flagAlias{set: set, name: name, alias: alias}
+	return nil
 }
 
 // Finish tells the set that the flags have all been accounted for, and it can forward all the flag
@@ -100,24 +902,235 @@ func (set *FlagSet) finish() {
 	for name, target := range set.targets {
 		set.Var(target, name, target.Usage())
 	}
+	for alias, target := range set.aliases {
+		if _, found := set.targets[alias]; found {
+			continue
+		} else if target.short {
+			set.Var(target, alias, "``Short for -"+target.name)
+		} else {
+			set.Var(target, alias, "``Deprecated alias of -"+target.name)
+		}
+	}
+}
+
+// flagAlias is the flag.Value of the old name of a flag, which sets the flag it stands for. Short
+// names are aliases too, but they are not deprecated.
+type flagAlias struct {
+	set   *FlagSet
+	name  string
+	alias string
+	short bool
+}
+
+func (alias *flagAlias) String() string { return "" }
+
+func (alias *flagAlias) IsBoolFlag() bool {
+	return alias.set.targets[alias.name].IsBoolFlag()
+}
+
+func (alias *flagAlias) Set(value string) error {
+	if alias.set.warnAliases && !alias.short {
+		fmt.Fprintf(alias.set.Output(), "warning: flag -%v is deprecated, use -%v instead\n", alias.alias, alias.name)
+	}
+	return alias.set.targets[alias.name].Set(value)
 }
 
+// addTarget binds the flag name to the field of the object. Binding a name twice is an error, unless
+// the set shares its flags, or shadows them, in which case the flag bound by structs at different
+// depths is the most deeply nested one.
 func (set *FlagSet) addTarget(name string, obj interface{}, field reflect.StructField, usage string) error {
 	target, found := set.targets[name]
-	if found {
-		return errors.Errorf("Duplicate binding of flag: %v", name)
+	if found && set.shared {
+		return target.share(newFlagTarget(obj, field, usage))
+	} else if found && set.shadow && target.depth > set.depth {
+		return nil
+	}
+
+	path := strings.Join(append(set.path, field.Name), ".")
+	if found && (target.depth == set.depth || !set.shadow) {
+		return set.duplicateError(name, target.path, path)
+	} else if alias, found := set.aliases[name]; found {
+		return set.duplicateError(name, set.targets[alias.name].path, path)
 	}
 	target = newFlagTarget(obj, field, usage)
+	target.depth, target.path, target.index = set.depth, path, set.declared
+	if set.depth > 0 {
+		target.group = set.path[len(set.path)-1]
+	}
 	set.targets[name] = target
+	set.declared++
 	return nil
 }
 
+// nest sets up the flags of the struct held by the field given, one level deeper than the current
+// one.
+func (set *FlagSet) nest(app interface{}, field string) error {
+	set.depth++
+	set.path = append(set.path, field)
+	defer func() {
+		set.depth--
+		set.path = set.path[:len(set.path)-1]
+	}()
+	return setupFlagSet(app, set)
+}
+
+// nestedStruct is a struct whose flags are being set up, with its path from the application.
+type nestedStruct struct {
+	obj  interface{}
+	path string
+}
+
+// enter records that the flags of the struct are being set up, and returns the function to call
+// once they are. It fails if the struct is already being set up higher in the chain of
+// flagstructs, which would otherwise make the set up recurse forever.
+func (set *FlagSet) enter(obj interface{}) (func(), error) {
+	if reflect.ValueOf(obj).Kind() != reflect.Ptr {
+		return func() {}, nil
+	}
+	path := strings.Join(set.path, ".")
+	for _, ancestor := range set.nesting {
+		if ancestor.obj == obj {
+			return nil, errors.Errorf("Cycle of flagstructs: %v refers back to %v", path, ancestor.path)
+		}
+	}
+	set.nesting = append(set.nesting, nestedStruct{obj: obj, path: path})
+	return func() { set.nesting = set.nesting[:len(set.nesting)-1] }, nil
+}
+
+func (set *FlagSet) duplicateError(name string, first, second string) error {
+	if set.command != "" {
+		return errors.Errorf("Duplicate binding of flag: %v and %v both bind --%v for command %v", first, second, name, set.command)
+	}
+	return errors.Errorf("Duplicate binding of flag: %v and %v both bind --%v", first, second, name)
+}
+
+// defaultFlagUsage is the usage of the flags whose directive does not give one.
+const defaultFlagUsage = "No usage found for this flag."
+
+// flagOptions are the options of a flag directive.
+type flagOptions struct {
+	name       string
+	usage      string
+	aliases    []string
+	short      string
+	env        string
+	def        string
+	required   bool
+	requiredIf []flagCondition
+	secret     bool
+	min, max   string
+
+	pattern        string
+	minlen, maxlen int
+	order          int
+	placeholder    string
+	group          string
+}
+
+// parseFlagTag parses the tag of a field if it is a flag directive, in either of its syntaxes:
+// the positional one, flag=<name>,<usage>[,alias=<old>]..., or the structured one,
+// flag,name=<name>[,<option>]... where the options are short=<name>, env=<variable>,
+// default=<value>, usage=<usage>, alias=<old>, required, requiredif=<flag>[=<value>] to require the
+// flag when another one is set or has a value, secret, min=<value> and max=<value> for numeric
+// flags, pattern=<regexp>, minlen=<n> and maxlen=<n> for string flags, order=<n> to move the flag
+// ahead in the usage, placeholder=<NAME> to show the flag as -<name> <NAME> in it and group=<name>
+// to list it under the "<name> options:" heading of the usage. Values can be single-quoted, or have
+// their commas escaped with a backslash, except for patterns, which keep their backslashes for the
+// regular expression. The boolean returned is false if the tag is not a flag directive.
+func parseFlagTag(tag string) (flagOptions, bool, error) {
+	if strings.HasPrefix(tag, FlagDirective+",") {
+		parsed, err := parseFlagOptions(strings.TrimPrefix(tag, FlagDirective+","))
+		return parsed, true, err
+	} else if strings.HasPrefix(tag, FlagDirective+"=") {
+		return parseFlagDirective(strings.TrimPrefix(tag, FlagDirective+"=")), true, nil
+	}
+	return flagOptions{}, false, nil
+}
+
 // ParseFlagDirective parses the directive into the flag's name and its usage. The format of a flag directive is
-// <name>,<usage>.
-func parseFlagDirective(directive string) (name string, usage string) {
-	split := strings.SplitN(directive, ",", 2)
-	if len(split) == 1 {
-		return directive, "No usage found for this flag."
+// <name>,<usage>, optionally followed by the ,alias=<old> options of the flag.
+func parseFlagDirective(directive string) flagOptions {
+	options := flagOptions{}
+	for {
+		rest, alias, found := cutDirectiveOption(directive, "alias")
+		if !found {
+			break
+		}
+		directive, options.aliases = rest, append([]string{alias}, options.aliases...)
+	}
+
+	options.name, options.usage = cutFirstDirectivePart(directive)
+	if options.usage == "" {
+		options.usage = defaultFlagUsage
+	}
+	return options
+}
+
+// parseFlagOptions parses the comma-separated options of a structured flag directive.
+func parseFlagOptions(directive string) (flagOptions, error) {
+	options := flagOptions{usage: defaultFlagUsage}
+	for _, option := range splitDirective(directive) {
+		split := append(strings.SplitN(option, "=", 2), "")
+		key, raw := split[0], split[1]
+		value := unquoteDirective(raw)
+		switch key {
+		case "name":
+			options.name = value
+		case "short":
+			options.short = value
+		case "env":
+			options.env = value
+		case "default":
+			options.def = value
+		case "usage":
+			options.usage = value
+		case "alias":
+			options.aliases = append(options.aliases, value)
+		case "required":
+			options.required = true
+		case "requiredif":
+			condition := strings.SplitN(value, "=", 2)
+			if condition[0] == "" {
+				return options, errors.Errorf("invalid flag option %q: expected a flag name", option)
+			}
+			required := flagCondition{name: condition[0], hasValue: len(condition) == 2}
+			if required.hasValue {
+				required.value = condition[1]
+			}
+			options.requiredIf = append(options.requiredIf, required)
+		case "secret":
+			options.secret = true
+		case "min":
+			options.min = value
+		case "max":
+			options.max = value
+		case "order":
+			order, err := strconv.Atoi(value)
+			if err != nil || order <= 0 {
+				return options, errors.Errorf("invalid flag option %q: expected a positive number", option)
+			}
+			options.order = order
+		case "placeholder":
+			options.placeholder = value
+		case "group":
+			options.group = value
+		case "pattern":
+			options.pattern = unquotePattern(raw)
+		case "minlen", "maxlen":
+			length, err := strconv.Atoi(value)
+			if err != nil || length < 0 {
+				return options, errors.Errorf("invalid flag option %q: expected a length", option)
+			} else if key == "minlen" {
+				options.minlen = length
+			} else {
+				options.maxlen = length
+			}
+		default:
+			return options, errors.Errorf("unknown flag option %q", option)
+		}
+	}
+	if options.name == "" {
+		return options, errors.Errorf("flag directive without a name option")
 	}
-	return split[0], split[1]
+	return options, nil
 }
@@ -1,6 +1,13 @@
 package commander_test
 
 import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"os/exec"
+	"strings"
 	"testing"
 	"time"
 
@@ -49,6 +56,16 @@ func TestFlagParsing(t *testing.T) {
 		require.Equal(t, "somestring", app.String)
 		require.Equal(t, 10, app.Int)
 	})
+
+	t.Run("yes_no", func(t *testing.T) {
+		app := &FlagTester{}
+		flagset, err := cmd.GetFlagSet(app, "CLI")
+		require.NoError(t, err)
+		require.NoError(t, flagset.Parse([]string{"--boolflag=yes"}))
+		require.True(t, app.Bool)
+		require.NoError(t, flagset.Parse([]string{"--boolflag=OFF"}))
+		require.False(t, app.Bool)
+	})
 }
 
 func TestFlagStringify(t *testing.T) {
@@ -123,6 +140,172 @@ func TestFlagStringify(t *testing.T) {
 	})
 }
 
+func TestFlagStringifyChanged(t *testing.T) {
+	cmd := commander.New()
+
+	app := &FlagTester{String: "default", Int: 3}
+	flagset, err := cmd.GetFlagSet(app, "CLI")
+	require.NoError(t, err)
+	require.Empty(t, flagset.StringifyChanged())
+
+	flagset.Parse([]string{"--intflag", "10"})
+	require.Equal(t, []string{"--intflag", "10"}, flagset.StringifyChanged())
+	require.Len(t, flagset.Stringify(), 4)
+
+	flagset.Parse([]string{"--stringflag", "default"})
+	changed := flagset.StringifyChanged()
+	require.Len(t, changed, 4)
+	require.Contains(t, strings.Join(changed, " "), "--intflag 10")
+	require.Contains(t, strings.Join(changed, " "), "--stringflag default")
+}
+
+func TestFlagStringifyShell(t *testing.T) {
+	app := &struct {
+		Str string `commander:"flag=str"`
+	}{Str: `it's "$HOME"` + "\n"}
+	flagset, err := commander.New().GetFlagSet(app, "CLI")
+	require.NoError(t, err)
+	require.Equal(t, `--str 'it'\''s "$HOME"`+"\n'", flagset.StringifyShell())
+
+	require.Equal(t, `a '' 'b c' d=e/f.g`, commander.ShellJoin([]string{"a", "", "b c", "d=e/f.g"}))
+}
+
+func TestShellSplit(t *testing.T) {
+	tests := map[string][]string{
+		"":                             {},
+		"  a\tb  ":                     {"a", "b"},
+		`a '' 'b c' d=e/f.g`:           {"a", "", "b c", "d=e/f.g"},
+		`'it'\''s' "say \"hi\" \n $x"`: {"it's", `say "hi" \n $x`},
+		`a\ b "c"'d'e`:                 {"a b", "cde"},
+		`subapp opfour '{"a":1}'`:      {"subapp", "opfour", `{"a":1}`},
+	}
+	for line, expected := range tests {
+		args, err := commander.ShellSplit(line)
+		require.NoError(t, err, line)
+		require.Equal(t, expected, args, line)
+	}
+
+	args := []string{"a", "", "b c", `it's "$HOME"` + "\n", `back\slash`}
+	split, err := commander.ShellSplit(commander.ShellJoin(args))
+	require.NoError(t, err)
+	require.Equal(t, args, split)
+
+	for _, line := range []string{`'open`, `"open`, `trailing\`} {
+		_, err := commander.ShellSplit(line)
+		require.Error(t, err, line)
+	}
+}
+
+func TestFlagInspection(t *testing.T) {
+	app := &struct {
+		FlagTester `commander:"flagstruct"`
+		Duration   time.Duration `commander:"flag=dur,A duration"`
+	}{}
+	app.Int = 3
+	flagset, err := commander.New().GetFlagSet(app, "CLI")
+	require.NoError(t, err)
+	flagset.Parse([]string{"--stringflag", "str", "--dur", "2s", "--boolflag"})
+
+	require.True(t, flagset.Changed("stringflag"))
+	require.False(t, flagset.Changed("intflag"))
+	require.False(t, flagset.Changed("notaflag"))
+
+	str, err := flagset.GetString("stringflag")
+	require.NoError(t, err)
+	require.Equal(t, "str", str)
+
+	i, err := flagset.GetInt("intflag")
+	require.NoError(t, err)
+	require.Equal(t, 3, i)
+
+	b, err := flagset.GetBool("boolflag")
+	require.NoError(t, err)
+	require.True(t, b)
+
+	dur, err := flagset.GetDuration("dur")
+	require.NoError(t, err)
+	require.Equal(t, 2*time.Second, dur)
+
+	_, err = flagset.GetInt("stringflag")
+	require.Error(t, err)
+	_, err = flagset.GetDuration("intflag")
+	require.Error(t, err)
+	_, err = flagset.GetString("notaflag")
+	require.Error(t, err)
+
+	targets := flagset.Targets()
+	require.Len(t, targets, 4)
+	require.Equal(t, "boolflag", targets[0].Name)
+	require.Equal(t, "A bool", targets[0].Usage)
+	require.True(t, targets[0].Changed)
+	require.Equal(t, "intflag", targets[2].Name)
+	require.Equal(t, "3", targets[2].Value)
+	require.False(t, targets[2].Changed)
+}
+
+func TestFlagMerge(t *testing.T) {
+	cmd := commander.New()
+	cmd.UsageOutput = ioutil.Discard
+	setup := func() (*FlagTester, *commander.FlagSet, *FlagTesterNested, *commander.FlagSet) {
+		app1 := &FlagTester{}
+		set1, err := cmd.GetFlagSet(app1, "CLI")
+		require.NoError(t, err)
+		app2 := &FlagTesterNested{Nested: &FlagTester{}}
+		set2, err := cmd.GetFlagSet(app2, "CLI")
+		require.NoError(t, err)
+		return app1, set1, app2, set2
+	}
+	args := []string{"--intflag", "10", "--innerint", "3", "--toplevel"}
+
+	t.Run("error", func(t *testing.T) {
+		_, set1, _, set2 := setup()
+		require.Error(t, set1.Merge(set2, commander.MergeError))
+		require.Nil(t, set1.Lookup("innerint"))
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		app1, set1, app2, set2 := setup()
+		require.NoError(t, set1.Merge(set2, commander.MergeSkip))
+		require.NoError(t, set1.Parse(args))
+		require.Equal(t, 10, app1.Int)
+		require.Equal(t, 0, app2.Nested.Int)
+		require.Equal(t, 3, app2.NestedNoPtr.Int)
+		require.True(t, app2.Toplevel)
+	})
+
+	t.Run("override", func(t *testing.T) {
+		app1, set1, app2, set2 := setup()
+		require.NoError(t, set1.Merge(set2, commander.MergeOverride))
+		require.NoError(t, set1.Parse(args))
+		require.Equal(t, 0, app1.Int)
+		require.Equal(t, 10, app2.Nested.Int)
+		require.Equal(t, 3, app2.NestedNoPtr.Int)
+	})
+}
+
+func TestDiffArgs(t *testing.T) {
+	base := &FlagTesterNested{Toplevel: true, Nested: &FlagTester{String: "a", Int: 1}}
+	modified := &FlagTesterNested{Nested: &FlagTester{String: "b c", Int: 1, Bool: true}}
+	modified.NestedNoPtr.Int = 5
+
+	args, err := commander.DiffArgs(base, modified)
+	require.NoError(t, err)
+	require.Equal(t, []string{"--boolflag", "--innerint", "5", "--stringflag", "b c", "--toplevel=false"}, args)
+
+	base = &FlagTesterNested{Toplevel: true, Nested: &FlagTester{String: "a", Int: 1}}
+	flagset, err := commander.New().GetFlagSet(base, "CLI")
+	require.NoError(t, err)
+	require.NoError(t, flagset.Parse(args))
+	args, err = commander.DiffArgs(base, modified)
+	require.NoError(t, err)
+	require.Empty(t, args)
+
+	_, err = commander.DiffArgs(1, modified)
+	require.Error(t, err)
+	_, err = commander.DiffArgs(base, 1)
+	require.Error(t, err)
+}
+
 func TestFlagDefaults(t *testing.T) {
 	cmd := commander.New()
 
@@ -194,6 +377,15 @@ func TestFlagParsingDuration(t *testing.T) {
 	flagset.Parse(args)
 	require.Equal(t, 4*time.Hour, app.Duration)
 	require.Equal(t, 2*time.Second, app.Nested.Duration)
+	require.Contains(t, strings.Join(flagset.Stringify(), " "), "--dur 4h0m0s")
+
+	app = &FlagDurationTester{Duration: 90 * time.Minute}
+	flagset, err = cmd.GetFlagSet(app, "CLI")
+	require.NoError(t, err)
+	usage := cmd.Usage(app)
+	require.Contains(t, usage, "A duration (type: duration, default: 1h30m0s)")
+	require.NoError(t, flagset.Parse([]string{"--dur", "1h30m0s"}))
+	require.Equal(t, 90*time.Minute, app.Duration)
 }
 
 type FlagTesterSliced struct {
@@ -223,3 +415,544 @@ func TestFlagParsingSliced(t *testing.T) {
 	require.Equal(t, 10, intflag.Value)
 	require.True(t, boolflag.Value)
 }
+
+type RegionFlagStruct struct {
+	Region string `commander:"flag=region,A region"`
+}
+
+type FlagTesterShared struct {
+	HTTP RegionFlagStruct `commander:"flagstruct"`
+	DB   RegionFlagStruct `commander:"flagstruct"`
+}
+
+type FlagTesterSharedCommand struct {
+	Cmd FlagTesterShared `commander:"flagstruct=cmd"`
+}
+
+func TestFlagParsingShared(t *testing.T) {
+	t.Run("duplicate", func(t *testing.T) {
+		_, err := commander.New().GetFlagSet(&FlagTesterShared{}, "CLI")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "FlagTesterShared.HTTP.Region and FlagTesterShared.DB.Region both bind --region")
+
+		_, err = commander.New().GetFlagSetWithCommand(&FlagTesterSharedCommand{}, "CLI", "cmd")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "FlagTesterSharedCommand.Cmd.HTTP.Region and FlagTesterSharedCommand.Cmd.DB.Region both bind --region for command cmd")
+	})
+
+	t.Run("shared", func(t *testing.T) {
+		cmd := commander.New()
+		cmd.AllowSharedFlags = true
+		app := &FlagTesterShared{}
+		flagset, err := cmd.GetFlagSet(app, "CLI")
+		require.NoError(t, err)
+		flagset.Parse([]string{"--region", "us-east"})
+		require.Equal(t, "us-east", app.HTTP.Region)
+		require.Equal(t, "us-east", app.DB.Region)
+	})
+
+	t.Run("conflicting_types", func(t *testing.T) {
+		cmd := commander.New()
+		cmd.AllowSharedFlags = true
+		app := &struct {
+			A RegionFlagStruct `commander:"flagstruct"`
+			B struct {
+				Region int `commander:"flag=region"`
+			} `commander:"flagstruct"`
+		}{}
+		_, err := cmd.GetFlagSet(app, "CLI")
+		require.Error(t, err)
+	})
+}
+
+type FormatFlagStruct struct {
+	Format string `commander:"flag=format,Output format of the command"`
+}
+
+type FlagTesterShadowed struct {
+	Nested FormatFlagStruct `commander:"flagstruct"`
+	Format string           `commander:"flag=format,Output format"`
+}
+
+func TestFlagParsingShadowed(t *testing.T) {
+	_, err := commander.New().GetFlagSet(&FlagTesterShadowed{}, "CLI")
+	require.EqualError(t, err, "failed to get flagset: failed to setup flag for application: Duplicate binding of flag: "+
+		"FlagTesterShadowed.Nested.Format and FlagTesterShadowed.Format both bind --format")
+
+	cmd := commander.New()
+	cmd.ShadowFlags = true
+	app := &FlagTesterShadowed{Format: "text"}
+	flagset, err := cmd.GetFlagSet(app, "CLI")
+	require.NoError(t, err)
+	require.NoError(t, flagset.Parse([]string{"--format", "json"}))
+	require.Equal(t, "json", app.Nested.Format)
+	require.Equal(t, "text", app.Format)
+
+	cmd = commander.New()
+	cmd.AllowSharedFlags = true
+	app = &FlagTesterShadowed{}
+	flagset, err = cmd.GetFlagSet(app, "CLI")
+	require.NoError(t, err)
+	require.NoError(t, flagset.Parse([]string{"--format", "json"}))
+	require.Equal(t, "json", app.Nested.Format)
+	require.Equal(t, "json", app.Format)
+}
+
+func TestFlagParsingDefinitions(t *testing.T) {
+	app := &struct {
+		Defines map[string]string `commander:"flag=X,Definitions"`
+		Limits  map[string]int    `commander:"flag=limit"`
+	}{Defines: map[string]string{"default": "value"}}
+	flagset, err := commander.New().GetFlagSet(app, "CLI")
+	require.NoError(t, err)
+	require.NoError(t, flagset.Parse([]string{"-X", "region=us", "-X", "tier=gold=plus", "--limit", "cpu=2"}))
+	require.Equal(t, map[string]string{"region": "us", "tier": "gold=plus"}, app.Defines)
+	require.Equal(t, map[string]int{"cpu": 2}, app.Limits)
+
+	require.NoError(t, flagset.Parse([]string{"-X", "a=1,b=2", "-X", "msg=hello, world"}))
+	require.Equal(t, map[string]string{"region": "us", "tier": "gold=plus", "a": "1", "b": "2", "msg": "hello, world"}, app.Defines)
+
+	require.NoError(t, flagset.Parse([]string{"-X", `{"json": "object"}`}))
+	require.Equal(t, map[string]string{"json": "object"}, app.Defines)
+
+	require.Error(t, flagset.Parse([]string{"--limit", "cpu"}))
+	require.Error(t, flagset.Parse([]string{"--limit", "cpu=many"}))
+}
+
+func TestFlagParsingAliases(t *testing.T) {
+	app := &struct {
+		Region  string `commander:"flag=region,The region,alias=zone,alias=location"`
+		Verbose bool   `commander:"flag=verbose,alias=debug"`
+	}{}
+
+	var buf bytes.Buffer
+	cmd := commander.New()
+	cmd.UsageOutput = &buf
+	flagset, err := cmd.GetFlagSet(app, "CLI")
+	require.NoError(t, err)
+	require.NoError(t, flagset.Parse([]string{"--zone", "us", "--debug"}))
+	require.Equal(t, "us", app.Region)
+	require.True(t, app.Verbose)
+	require.NoError(t, flagset.Parse([]string{"--location=eu"}))
+	require.Equal(t, "eu", app.Region)
+	require.Len(t, flagset.Stringify(), 3)
+	require.Contains(t, strings.Join(flagset.Stringify(), " "), "--region eu")
+	require.Contains(t, flagset.Stringify(), "--verbose")
+	require.Empty(t, buf.String())
+
+	cmd.WarnFlagAliases = true
+	flagset, err = cmd.GetFlagSet(app, "CLI")
+	require.NoError(t, err)
+	require.NoError(t, flagset.Parse([]string{"--zone", "us"}))
+	require.Equal(t, "warning: flag -zone is deprecated, use -region instead\n", buf.String())
+
+	_, err = cmd.GetFlagSet(&struct {
+		Region string `commander:"flag=region,alias=zone"`
+		Zone   string `commander:"flag=zone"`
+	}{}, "CLI")
+	require.Error(t, err)
+}
+
+type StructuredFlagApplication struct {
+	Port    int    `commander:"flag,name=port,short=p,env=COMMANDER_TEST_PORT,default=8080,usage=Listen port"`
+	Host    string `commander:"flag,name=host,required"`
+	Verbose bool   `commander:"flag,name=verbose,short=v"`
+}
+
+func TestFlagParsingStructured(t *testing.T) {
+	var buf bytes.Buffer
+	cmd := commander.New()
+	cmd.UsageOutput = &buf
+	cmd.WarnFlagAliases = true
+
+	app := &StructuredFlagApplication{}
+	flagset, err := cmd.GetFlagSet(app, "CLI")
+	require.NoError(t, err)
+	require.Equal(t, 8080, app.Port)
+	require.NoError(t, flagset.Parse([]string{"-p", "80", "-v", "--host", "localhost"}))
+	require.Equal(t, 80, app.Port)
+	require.True(t, app.Verbose)
+	require.Equal(t, "localhost", app.Host)
+	require.Empty(t, buf.String())
+
+	flagset.PrintDefaults()
+	require.Contains(t, buf.String(), "Listen port (type: int, default: 8080)")
+	require.Contains(t, buf.String(), "Short for -port")
+
+	app = &StructuredFlagApplication{}
+	flagset, err = cmd.GetFlagSet(app, "CLI")
+	require.NoError(t, err)
+	require.EqualError(t, flagset.Parse([]string{"-p", "80"}), "Missing required flags: -host")
+
+	os.Setenv("COMMANDER_TEST_PORT", "9090")
+	defer os.Unsetenv("COMMANDER_TEST_PORT")
+	app = &StructuredFlagApplication{}
+	flagset, err = cmd.GetFlagSet(app, "CLI")
+	require.NoError(t, err)
+	require.NoError(t, flagset.Parse([]string{"--host", "localhost"}))
+	require.Equal(t, 9090, app.Port)
+
+	app = &StructuredFlagApplication{Port: 1}
+	flagset, err = cmd.GetFlagSet(app, "CLI")
+	require.NoError(t, err)
+	require.NoError(t, flagset.Parse([]string{"--host", "localhost", "--port", "2"}))
+	require.Equal(t, 2, app.Port)
+
+	_, err = cmd.GetFlagSet(&struct {
+		Port int `commander:"flag,short=p"`
+	}{}, "CLI")
+	require.Error(t, err)
+}
+
+type ConditionalFlagApplication struct {
+	TLS     bool   `commander:"flag,name=tls"`
+	Cert    string `commander:"flag,name=cert,requiredif=tls=true"`
+	Mode    string `commander:"flag,name=mode,default=plain"`
+	Key     string `commander:"flag,name=key,requiredif=cert,requiredif=mode=secure"`
+	Unknown string `commander:"flag,name=unknown,requiredif=nope"`
+}
+
+func TestFlagParsingRequiredIf(t *testing.T) {
+	cmd := commander.New()
+	cmd.UsageOutput = ioutil.Discard
+	tests := map[string]string{
+		"":                             "Flag -nope in a requiredif option is not defined",
+		"--unknown=x":                  "",
+		"--unknown=x --tls":            "Missing required flags: -cert (required when -tls is true)",
+		"--unknown=x --tls=yes":        "Missing required flags: -cert (required when -tls is true)",
+		"--unknown=x --tls --cert=c":   "Missing required flags: -key (required when -cert is set)",
+		"--unknown=x --mode=secure":    "Missing required flags: -key (required when -mode is secure)",
+		"--unknown=x --cert=c --key=k": "",
+	}
+	for args, expected := range tests {
+		flagset, err := cmd.GetFlagSet(&ConditionalFlagApplication{}, "CLI")
+		require.NoError(t, err)
+		err = flagset.Parse(strings.Fields(args))
+		if expected == "" {
+			require.NoError(t, err, args)
+		} else {
+			require.EqualError(t, err, expected, args)
+		}
+	}
+
+	_, err := cmd.GetFlagSet(&struct {
+		Cert string `commander:"flag,name=cert,requiredif="`
+	}{}, "CLI")
+	require.Error(t, err)
+}
+
+type RangedFlagApplication struct {
+	Port    int           `commander:"flag,name=port,min=1,max=65535,usage=Listen port"`
+	Workers uint          `commander:"flag,name=workers,min=1"`
+	Ratio   float64       `commander:"flag,name=ratio,max=0.5"`
+	Wait    time.Duration `commander:"flag,name=wait,min=1s,max=1m"`
+}
+
+func TestFlagParsingRange(t *testing.T) {
+	var buf bytes.Buffer
+	cmd := commander.New()
+	cmd.UsageOutput = &buf
+	app := &RangedFlagApplication{Port: 80}
+	flagset, err := cmd.GetFlagSet(app, "CLI")
+	require.NoError(t, err)
+	require.NoError(t, flagset.Parse([]string{"--port", "65535", "--workers", "1", "--ratio", "0.5", "--wait", "1m"}))
+	require.Equal(t, 65535, app.Port)
+	require.Equal(t, time.Minute, app.Wait)
+
+	tests := map[string]string{
+		"port=0":     `invalid value "0" for flag -port: must be between 1 and 65535`,
+		"port=65536": `invalid value "65536" for flag -port: must be between 1 and 65535`,
+		"workers=0":  `invalid value "0" for flag -workers: must be at least 1`,
+		"ratio=0.51": `invalid value "0.51" for flag -ratio: must be at most 0.5`,
+		"wait=500ms": `invalid value "500ms" for flag -wait: must be between 1s and 1m`,
+	}
+	for arg, expected := range tests {
+		buf.Reset()
+		flagset, err = cmd.GetFlagSet(&RangedFlagApplication{}, "CLI")
+		require.NoError(t, err)
+		require.EqualError(t, flagset.Parse([]string{"--" + arg}), expected)
+	}
+	require.Equal(t, 65535, app.Port)
+
+	buf.Reset()
+	flagset.PrintDefaults()
+	require.Contains(t, buf.String(), "Listen port (type: int, default: 0, min: 1, max: 65535)")
+
+	_, err = cmd.GetFlagSet(&struct {
+		Name string `commander:"flag,name=name,min=1"`
+	}{}, "CLI")
+	require.Error(t, err)
+	_, err = cmd.GetFlagSet(&struct {
+		Port int `commander:"flag,name=port,max=many"`
+	}{}, "CLI")
+	require.Error(t, err)
+}
+
+type TextFlagApplication struct {
+	Name   string `commander:"flag,name=name,pattern=^[a-z0-9-]+$,minlen=3,maxlen=8,usage=Name of the cluster"`
+	Region string `commander:"flag,name=region,pattern='^[a-z]{2,3}$'"`
+	Port   string `commander:"flag,name=port,pattern=^\\d+$"`
+	Host   string `commander:"flag,name=host,pattern='^[a-z]+\\.example\\.com$'"`
+}
+
+func TestFlagParsingText(t *testing.T) {
+	var buf bytes.Buffer
+	cmd := commander.New()
+	cmd.UsageOutput = &buf
+	app := &TextFlagApplication{}
+	flagset, err := cmd.GetFlagSet(app, "CLI")
+	require.NoError(t, err)
+	require.NoError(t, flagset.Parse([]string{"--name", "prod-1", "--region", "eu", "--port", "8080", "--host", "www.example.com"}))
+	require.Equal(t, "prod-1", app.Name)
+	require.Equal(t, "eu", app.Region)
+	require.Equal(t, "8080", app.Port)
+	require.Equal(t, "www.example.com", app.Host)
+
+	tests := map[string]string{
+		"name=Prod":            `invalid value "Prod" for flag -name: must match ^[a-z0-9-]+$`,
+		"name=ab":              `invalid value "ab" for flag -name: must be at least 3 characters long`,
+		"name=abcdefghi":       `invalid value "abcdefghi" for flag -name: must be at most 8 characters long`,
+		"region=euro":          `invalid value "euro" for flag -region: must match ^[a-z]{2,3}$`,
+		"port=ddd":             `invalid value "ddd" for flag -port: must match ^\d+$`,
+		"host=wwwxexample.com": `invalid value "wwwxexample.com" for flag -host: must match ^[a-z]+\.example\.com$`,
+	}
+	for arg, expected := range tests {
+		flagset, err = cmd.GetFlagSet(&TextFlagApplication{}, "CLI")
+		require.NoError(t, err)
+		require.EqualError(t, flagset.Parse([]string{"--" + arg}), expected)
+	}
+
+	buf.Reset()
+	flagset.PrintDefaults()
+	require.Contains(t, buf.String(), `Name of the cluster (type: string, default: "", pattern: ^[a-z0-9-]+$, minlen: 3, maxlen: 8)`)
+
+	_, err = cmd.GetFlagSet(&struct {
+		Port int `commander:"flag,name=port,maxlen=3"`
+	}{}, "CLI")
+	require.Error(t, err)
+	_, err = cmd.GetFlagSet(&struct {
+		Name string `commander:"flag,name=name,pattern=[a-z"`
+	}{}, "CLI")
+	require.Error(t, err)
+	_, err = cmd.GetFlagSet(&struct {
+		Name string `commander:"flag,name=name,minlen=short"`
+	}{}, "CLI")
+	require.Error(t, err)
+}
+
+type EnvOnlyApplication struct {
+	Host  string `commander:"flag,name=host,env=COMMANDER_TEST_HOST"`
+	Token string `commander:"env=COMMANDER_TEST_TOKEN,API token, never passed as a flag"`
+	Retry int    `commander:"env=COMMANDER_TEST_RETRY"`
+}
+
+func TestFlagParsingEnvOnly(t *testing.T) {
+	cmd := commander.New()
+	cmd.UsageOutput = ioutil.Discard
+	app := &EnvOnlyApplication{Retry: 3}
+	flagset, err := cmd.GetFlagSet(app, "CLI")
+	require.NoError(t, err)
+	require.Nil(t, flagset.Lookup("token"))
+	require.Len(t, flagset.Targets(), 1)
+	require.NoError(t, flagset.Parse(nil))
+	require.Equal(t, "", app.Token)
+	require.Equal(t, 3, app.Retry)
+
+	os.Setenv("COMMANDER_TEST_TOKEN", "secret")
+	defer os.Unsetenv("COMMANDER_TEST_TOKEN")
+	os.Setenv("COMMANDER_TEST_RETRY", "5")
+	defer os.Unsetenv("COMMANDER_TEST_RETRY")
+	flagset, err = cmd.GetFlagSet(app, "CLI")
+	require.NoError(t, err)
+	require.NoError(t, flagset.Parse(nil))
+	require.Equal(t, "secret", app.Token)
+	require.Equal(t, 5, app.Retry)
+	require.Error(t, flagset.Parse([]string{"--token", "other"}))
+
+	os.Setenv("COMMANDER_TEST_RETRY", "many")
+	flagset, err = cmd.GetFlagSet(app, "CLI")
+	require.NoError(t, err)
+	require.Error(t, flagset.Parse(nil))
+
+	usage := cmd.Usage(&EnvOnlyApplication{})
+	require.Contains(t, usage, "\nEnvironment:\n"+
+		"  COMMANDER_TEST_HOST  |  Default of -host\n"+
+		"  COMMANDER_TEST_RETRY  |  No description for this variable\n"+
+		"  COMMANDER_TEST_TOKEN  |  API token, never passed as a flag\n")
+}
+
+func TestFlagParsingQuotedOptions(t *testing.T) {
+	app := &struct {
+		Tags   []string `commander:"flag,name=tags,default='[\"a\",\"b\"]',usage='Tags, as key=value pairs'"`
+		Region string   `commander:"flag=region,'The region, like us-east=1',alias=zone"`
+		Mode   string   `commander:"flag=mode,Don't use a comma\\,alias=here"`
+	}{}
+
+	cmd := commander.New()
+	cmd.UsageOutput = ioutil.Discard
+	flagset, err := cmd.GetFlagSet(app, "CLI")
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, app.Tags)
+	require.NoError(t, flagset.Parse([]string{"--zone", "us"}))
+	require.Equal(t, "us", app.Region)
+
+	usages := map[string]string{}
+	for _, info := range flagset.Targets() {
+		usages[info.Name] = info.Usage
+	}
+	require.Equal(t, "Tags, as key=value pairs", usages["tags"])
+	require.Equal(t, "The region, like us-east=1", usages["region"])
+	require.Equal(t, "Don't use a comma,alias=here", usages["mode"])
+}
+
+func TestFlagParsingYAMLCollections(t *testing.T) {
+	app := &struct {
+		Tags   []string          `commander:"flag=tags"`
+		Labels map[string]string `commander:"flag=labels"`
+	}{}
+	flagset, err := commander.New().GetFlagSet(app, "CLI")
+	require.NoError(t, err)
+	require.NoError(t, flagset.Parse([]string{"--tags", "a,b", "--labels", "env: prod\nteam: core"}))
+	require.Equal(t, []string{"a", "b"}, app.Tags)
+	require.Equal(t, map[string]string{"env": "prod", "team": "core"}, app.Labels)
+
+	require.NoError(t, flagset.Parse([]string{"--tags", "[c, 'd e']", "--labels", "{env: dev}"}))
+	require.Equal(t, []string{"c", "d e"}, app.Tags)
+	require.Equal(t, map[string]string{"env": "dev"}, app.Labels)
+
+	require.NoError(t, flagset.Parse([]string{"--tags", "hello, world"}))
+	require.Equal(t, []string{"hello, world"}, app.Tags)
+}
+
+func TestFlagErrorHandling(t *testing.T) {
+	if os.Getenv("COMMANDER_TEST_EXIT") != "" {
+		cmd := commander.New()
+		cmd.UsageOutput = os.Stdout
+		cmd.FlagErrorHandling = flag.ExitOnError
+		cmd.RunCLI(&Application{}, strings.Fields(os.Getenv("COMMANDER_TEST_EXIT")))
+		os.Exit(3)
+	}
+
+	t.Run("continue", func(t *testing.T) {
+		var buf bytes.Buffer
+		cmd := commander.New()
+		cmd.UsageOutput = &buf
+		err := cmd.RunCLI(&Application{}, []string{"--intflg", "3", "opthree"})
+		require.EqualError(t, err, "flag provided but not defined: -intflg; did you mean -intflag?")
+		require.Equal(t, 1, strings.Count(buf.String(), "flag provided but not defined: -intflg"))
+		require.Equal(t, 1, strings.Count(buf.String(), "did you mean -intflag?"))
+	})
+
+	t.Run("exit", func(t *testing.T) {
+		// TestMain renames the test binary in os.Args.
+		binary, err := os.Executable()
+		require.NoError(t, err)
+		for args, code := range map[string]int{"--intflg 3 opthree": 2, "--help": 0} {
+			child := exec.Command(binary, "-test.run=^TestFlagErrorHandling$")
+			child.Env = append(os.Environ(), "COMMANDER_TEST_EXIT="+args)
+			out, err := child.CombinedOutput()
+			if code == 0 {
+				require.NoError(t, err, string(out))
+			} else {
+				require.Error(t, err)
+				require.Equal(t, code, err.(*exec.ExitError).ExitCode())
+				require.Equal(t, 1, strings.Count(string(out), "flag provided but not defined: -intflg"), string(out))
+				require.Equal(t, 1, strings.Count(string(out), "did you mean -intflag?"), string(out))
+			}
+			require.Equal(t, 1, strings.Count(string(out), "Usage of myapp:"), string(out))
+		}
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		cmd := commander.New()
+		cmd.UsageOutput = ioutil.Discard
+		cmd.FlagErrorHandling = flag.PanicOnError
+		require.Panics(t, func() { cmd.RunCLI(&Application{}, []string{"--intflg", "3", "opthree"}) })
+		require.NotPanics(t, func() { cmd.RunCLI(&Application{}, []string{"opthree"}) })
+	})
+}
+
+type BigFlagTester struct {
+	Amount *big.Int   `commander:"flag=amount,An amount of any size"`
+	Ratio  big.Float  `commander:"flag=ratio,A precise ratio"`
+	Root   complex128 `commander:"flag=root,A complex root"`
+}
+
+func TestFlagParsingBigAndComplex(t *testing.T) {
+	app := &BigFlagTester{}
+	flagset, err := commander.New().GetFlagSet(app, "CLI")
+	require.NoError(t, err)
+	require.NoError(t, flagset.Parse([]string{"--amount=1_000_000_000_000_000_000_000", "--ratio=0.1", "--root=1-2i"}))
+	require.Equal(t, "1000000000000000000000", app.Amount.String())
+	require.Equal(t, "0.1", app.Ratio.Text('g', -1))
+	require.Equal(t, complex(1, -2), app.Root)
+
+	var usage bytes.Buffer
+	flagset.SetOutput(&usage)
+	flagset.PrintDefaults()
+	require.Contains(t, usage.String(), "type: big.Int")
+	require.Contains(t, usage.String(), "type: big.Float")
+	require.Contains(t, usage.String(), "type: complex128")
+}
+
+type GroupedFlagTester struct {
+	Verbose bool `commander:"flag=verbose,Print more"`
+
+	HTTP struct {
+		Port int `commander:"flag=port,Port to listen on"`
+	} `commander:"flagstruct"`
+	Database struct {
+		Host  string `commander:"flag=db-host,Database host"`
+		Debug bool   `commander:"flag,name=debug,usage=Print the queries,group=Logging"`
+	} `commander:"flagstruct"`
+}
+
+func TestFlagUsageGroups(t *testing.T) {
+	flagset, err := commander.New().GetFlagSet(&GroupedFlagTester{}, "CLI")
+	require.NoError(t, err)
+
+	var usage bytes.Buffer
+	flagset.SetOutput(&usage)
+	flagset.PrintDefaults()
+	expected := `  -verbose
+    	Print more (type: bool, default: false)
+
+HTTP options:
+  -port
+    	Port to listen on (type: int, default: 0)
+
+Database options:
+  -db-host
+    	Database host (type: string, default: "")
+
+Logging options:
+  -debug
+    	Print the queries (type: bool, default: false)
+`
+	require.Equal(t, expected, usage.String())
+}
+
+func TestBind(t *testing.T) {
+	fs := flag.NewFlagSet("plain", flag.ContinueOnError)
+	debug := fs.Bool("debug", false, "Debug")
+	app := &StructuredFlagApplication{}
+	require.NoError(t, commander.Bind(fs, app))
+	require.Equal(t, 8080, app.Port)
+
+	require.NoError(t, fs.Parse([]string{"-debug", "-v", "-p", "80", "--host", "localhost", "rest"}))
+	require.True(t, *debug)
+	require.True(t, app.Verbose)
+	require.Equal(t, 80, app.Port)
+	require.Equal(t, "localhost", app.Host)
+	require.Equal(t, []string{"rest"}, fs.Args())
+
+	nested := &FlagTesterNested{}
+	nestedfs := flag.NewFlagSet("nested", flag.ContinueOnError)
+	require.NoError(t, commander.Bind(nestedfs, nested))
+	require.NoError(t, nestedfs.Parse([]string{"--toplevel", "--innerint", "3"}))
+	require.True(t, nested.Toplevel)
+	require.Equal(t, 3, nested.NestedNoPtr.Int)
+
+	err := commander.Bind(fs, &StructuredFlagApplication{})
+	require.EqualError(t, err, "failed to bind flags: flag -host is already defined")
+}
@@ -0,0 +1,57 @@
+package commander
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// The ANSI escape sequences that FormatError colors errors with.
+const (
+	colorRed   = "\x1b[31m"
+	colorCyan  = "\x1b[36m"
+	colorBold  = "\x1b[1m"
+	colorReset = "\x1b[0m"
+)
+
+// FormatError renders the error returned by RunCLI for the user. A UsageError is rendered on
+// several lines: its cause, the command line with the argument at fault underlined and its hint.
+// Other errors are rendered on a single line.
+//
+//	error: failed to find possible method: [statsu]
+//	  petstore statsu --all
+//	           ^^^^^^
+//	hint: did you mean status?
+func (commander Commander) FormatError(err error) string {
+	color := func(code string, text string) string {
+		if !commander.ColorErrors || commander.noColor || os.Getenv(NoColorEnv) != "" {
+			return text
+		}
+		return code + text + colorReset
+	}
+
+	usage, ok := errors.Cause(err).(UsageError)
+	if !ok {
+		return fmt.Sprintf("%v %v\n", color(colorBold+colorRed, "error:"), err)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%v %v\n", color(colorBold+colorRed, "error:"), usage.Cause)
+	for i, arg := range usage.Arguments {
+		if usage.Token == "" || arg != usage.Token {
+			continue
+		}
+		before := strings.Join(append(strings.Fields(usage.Command), usage.Arguments[:i]...), " ") + " "
+		after := strings.TrimRight(" "+strings.Join(usage.Arguments[i+1:], " "), " ")
+		fmt.Fprintf(&buf, "  %v%v%v\n", before, color(colorBold, arg), after)
+		fmt.Fprintf(&buf, "  %v%v\n", strings.Repeat(" ", len(before)), color(colorRed, strings.Repeat("^", len(arg))))
+		break
+	}
+	if usage.Hint != "" {
+		fmt.Fprintf(&buf, "%v %v\n", color(colorBold+colorCyan, "hint:"), usage.Hint)
+	}
+	return buf.String()
+}
@@ -0,0 +1,76 @@
+package commander_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/apourchet/commander"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatError(t *testing.T) {
+	cmd := commander.New()
+	cmd.UsageOutput = ioutil.Discard
+
+	t.Run("unknown_command", func(t *testing.T) {
+		err := cmd.RunCLI(&Application{}, []string{"optree", "a"})
+		require.Error(t, err)
+		usage, ok := errors.Cause(err).(commander.UsageError)
+		require.True(t, ok)
+		require.Equal(t, "optree", usage.Token)
+		require.Equal(t, "did you mean opthree?", usage.Hint)
+
+		expected := `error: failed to find possible method: [optree CommanderDefault]
+  myapp optree a
+        ^^^^^^
+hint: did you mean opthree?
+`
+		require.Equal(t, expected, cmd.FormatError(err))
+	})
+
+	t.Run("unknown_flag", func(t *testing.T) {
+		err := cmd.RunCLI(&Application{}, []string{"--intflg=3", "opone", "test"})
+		require.EqualError(t, err, "flag provided but not defined: -intflg; did you mean -intflag?")
+		expected := `error: flag provided but not defined: -intflg
+  myapp --intflg=3 opone test
+        ^^^^^^^^^^
+hint: did you mean -intflag?
+`
+		require.Equal(t, expected, cmd.FormatError(err))
+	})
+
+	t.Run("arity", func(t *testing.T) {
+		err := cmd.RunCLI(&Application{}, []string{"opone", "a", "b"})
+		require.Error(t, err)
+		expected := `error: command requires 1 arguments, have 2
+  myapp opone a b
+                ^
+hint: usage: myapp opone <string>
+`
+		require.Equal(t, expected, cmd.FormatError(err))
+	})
+
+	t.Run("synopsis", func(t *testing.T) {
+		err := cmd.RunCLI(&ScopingApplication{Manage: &CopyApplication{}}, []string{"manage", "copy", "a"})
+		require.EqualError(t, err, "failed to run application: command requires 2 arguments, have 1; usage: CLI manage copy <src> <dst>")
+
+		err = cmd.RunCLI(&Application{}, []string{"opspread"})
+		require.EqualError(t, err, "failed to run application: command requires 1 arguments, have 0; usage: myapp opspread <string> [<string>...]")
+
+		err = cmd.RunCLI(&Application{}, []string{"opsince", "2024-01-01"})
+		require.EqualError(t, err, "failed to run application: command requires 2 arguments, have 1; usage: myapp opsince <time> <duration>")
+	})
+
+	t.Run("colors", func(t *testing.T) {
+		colored := cmd
+		colored.ColorErrors = true
+		require.Equal(t, "\x1b[1m\x1b[31merror:\x1b[0m boom\n", colored.FormatError(fmt.Errorf("boom")))
+
+		os.Setenv(commander.NoColorEnv, "1")
+		defer os.Unsetenv(commander.NoColorEnv)
+		require.Equal(t, "error: boom\n", colored.FormatError(fmt.Errorf("boom")))
+	})
+}
@@ -0,0 +1,129 @@
+package commander
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+
+	"github.com/apourchet/commander/utils"
+)
+
+// interfaceMethods are the methods that the interfaces of this package add to applications. They
+// are left out of the command graph although they could technically be called as commands.
+var interfaceMethods = map[string]bool{
+	"CLIName":               true,
+	"CommanderFallback":     true,
+	"PostFlagParse":         true,
+	"PostFlagParseContext":  true,
+	"PreDispatch":           true,
+	"GetCommandDescription": true,
+	"GetArgumentNames":      true,
+	"GetArgumentCount":      true,
+	"GetAllowedCommands":    true,
+	"CommandNames":          true,
+	"GetHelpTopics":         true,
+	"LongDescription":       true,
+	"SetCommandPath":        true,
+	"Subcommands":           true,
+	"Validate":              true,
+}
+
+// WriteGraph writes the command tree of the application as a Graphviz DOT graph: one box per
+// application and subcommand with the flags it defines, and one ellipse per command with the flags
+// of its flagstructs. Render it with `dot -Tsvg`.
+func (commander Commander) WriteGraph(w io.Writer, app interface{}) error {
+	appname := getCLIName(app)
+	fmt.Fprintf(w, "digraph %q {\n", appname)
+	fmt.Fprintf(w, "  node [shape=box];\n")
+	commander.UsageOutput = ioutil.Discard
+	if err := commander.writeGraphNode(w, app, appname, map[interface{}]bool{}); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "}\n")
+	return err
+}
+
+func (commander Commander) writeGraphNode(w io.Writer, app interface{}, appname string, visited map[interface{}]bool) error {
+	if reflect.ValueOf(app).Kind() == reflect.Ptr {
+		if visited[app] {
+			return nil
+		}
+		visited[app] = true
+	}
+
+	flagset, err := commander.GetFlagSet(app, appname)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "  %q [label=%q];\n", appname, graphLabel(lastWord(appname), flagset))
+
+	apptype := reflect.TypeOf(app)
+	for i := 0; i < apptype.NumMethod(); i++ {
+		method := apptype.Method(i)
+		if interfaceMethods[method.Name] || !isAllowedCommand(app, commandName(app, method)) {
+			continue
+		}
+		cmd := commandName(app, method)
+		node := appname + " " + cmd
+		cmdset, err := commander.GetFlagSetWithCommand(app, appname, cmd)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "  %q [shape=ellipse, label=%q];\n", node, graphLabel(cmd, cmdset))
+		fmt.Fprintf(w, "  %q -> %q;\n", appname, node)
+	}
+
+	st, valid := utils.DerefType(app)
+	if !valid {
+		return nil
+	}
+	subcommands := []string{}
+	for i := 0; i < st.NumField(); i++ {
+		split := strings.SplitN(st.Field(i).Tag.Get(FieldTag), "=", 2)
+		if len(split) != 2 || split[0] != SubcommandDirective {
+			continue
+		}
+		if directive, _ := parseSubcommandDirective(split[1]); directive.renamed == "" {
+			subcommands = append(subcommands, directive.cmd)
+		}
+	}
+	subcommands = append(subcommands, dynamicSubcommandNames(app)...)
+
+	for _, cmd := range subcommands {
+		subapp, _, err := subCommand(app, cmd)
+		if err != nil {
+			return err
+		} else if subapp == nil {
+			continue
+		}
+		if v := reflect.ValueOf(subapp); v.Kind() == reflect.Ptr && v.IsNil() {
+			// Graph the structure of subcommands that were not instantiated.
+			subapp = reflect.New(v.Type().Elem()).Interface()
+		}
+		node := appname + " " + cmd
+		fmt.Fprintf(w, "  %q -> %q;\n", appname, node)
+		if err := commander.writeGraphNode(w, subapp, node, visited); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// graphLabel returns the label of a node, made of its name and of the flags of the flagset.
+func graphLabel(name string, flagset *FlagSet) string {
+	lines := []string{name}
+	for _, info := range flagset.Targets() {
+		lines = append(lines, fmt.Sprintf("-%v %v", info.Name, info.Type))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func lastWord(str string) string {
+	words := strings.Fields(str)
+	if len(words) == 0 {
+		return str
+	}
+	return words[len(words)-1]
+}
@@ -0,0 +1,23 @@
+package commander_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/apourchet/commander"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteGraph(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, commander.New().WriteGraph(&buf, &Application3{}))
+	require.Equal(t, `digraph "CLI" {
+  node [shape=box];
+  "CLI" [label="CLI\n-a string"];
+  "CLI cmd1" [shape=ellipse, label="cmd1\n-b2 string\n-common string"];
+  "CLI" -> "CLI cmd1";
+  "CLI cmd2" [shape=ellipse, label="cmd2\n-c2 string\n-common string"];
+  "CLI" -> "CLI cmd2";
+}
+`, buf.String())
+}
@@ -0,0 +1,240 @@
+package commander
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+
+	"github.com/apourchet/commander/utils"
+)
+
+// HelpCommand is the command that prints the detailed help of a single flag, as in
+// "app help --flag name". The same help is printed for "app --help=name".
+const HelpCommand = "help"
+
+// flagDefinition is a flag bound somewhere in an application, along with the command path that
+// it applies to.
+type flagDefinition struct {
+	name    string
+	target  *flagTarget
+	aliases []string
+	scope   string
+}
+
+// FlagHelp returns the detailed help of the flag named: its usage, its type, its default value, its
+// aliases, its environment variable and its constraints, along with all the commands that it applies
+// to. Flags that are bound differently in different places of the application are described once per
+// binding.
+func (commander Commander) FlagHelp(app interface{}, name string) (string, error) {
+	name = strings.TrimLeft(name, "-")
+	definitions := []flagDefinition{}
+	collectFlagDefinitions(app, getCLIName(app), &definitions, map[interface{}]bool{})
+
+	var buf bytes.Buffer
+	var described []string
+	scopes := map[int][]string{}
+	for _, definition := range definitions {
+		if definition.name != name {
+			continue
+		}
+		details := definition.details()
+		index := len(described)
+		for i, other := range described {
+			if other == details {
+				index = i
+				break
+			}
+		}
+		if index == len(described) {
+			described = append(described, details)
+		}
+		scopes[index] = append(scopes[index], definition.scope)
+	}
+
+	if len(described) == 0 {
+		candidates := []flagCandidate{}
+		for _, definition := range definitions {
+			candidates = append(candidates, flagCandidate{name: definition.name, hint: "flag of " + definition.scope})
+		}
+		if suggestions := suggestFlags(name, candidates); len(suggestions) > 0 {
+			return "", fmt.Errorf("unknown flag -%v; did you mean %v?", name, strings.Join(suggestions, ", "))
+		}
+		return "", fmt.Errorf("unknown flag -%v", name)
+	}
+
+	for i, details := range described {
+		if i > 0 {
+			fmt.Fprintln(&buf)
+		}
+		fmt.Fprintf(&buf, "-%v\n", name)
+		fmt.Fprint(&buf, details)
+		fmt.Fprintf(&buf, "  Applies to: %v\n", strings.Join(scopes[i], ", "))
+	}
+	return buf.String(), nil
+}
+
+// details returns the lines of the help of the flag that describe its binding: everything but the
+// name of the flag and the commands that it applies to. The lines of the options that the flag does
+// not have are left out.
+func (definition flagDefinition) details() string {
+	target := definition.target
+	def := target.value()
+	if target.field.Type.Kind() == reflect.String {
+		def = fmt.Sprintf("%q", def)
+	}
+
+	var buf bytes.Buffer
+	line := func(label string, value interface{}) {
+		fmt.Fprintf(&buf, "  %-12v%v\n", label+":", value)
+	}
+	fmt.Fprintf(&buf, "    %v\n", target.usage)
+	line("Type", target.field.Type)
+	line("Default", def)
+	if len(definition.aliases) > 0 {
+		line("Aliases", strings.Join(definition.aliases, ", "))
+	}
+	if target.env != "" {
+		line("Env", target.env)
+	}
+	if target.required {
+		line("Required", "yes")
+	} else if len(target.requiredIf) > 0 {
+		conditions := []string{}
+		for _, condition := range target.requiredIf {
+			conditions = append(conditions, condition.String())
+		}
+		line("Required", "when "+strings.Join(conditions, " or "))
+	}
+	if target.min != "" {
+		line("Min", target.min)
+	}
+	if target.max != "" {
+		line("Max", target.max)
+	}
+	if target.pattern != nil {
+		line("Pattern", target.pattern)
+	}
+	if target.minlen > 0 {
+		line("Min length", target.minlen)
+	}
+	if target.maxlen > 0 {
+		line("Max length", target.maxlen)
+	}
+	return buf.String()
+}
+
+// PrintFlagHelp prints the detailed help of the flag named to the usage output of the Commander.
+func (commander Commander) PrintFlagHelp(app interface{}, name string) error {
+	help, err := commander.FlagHelp(app, name)
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(commander.UsageOutput, help)
+	return nil
+}
+
+// collectFlagDefinitions adds the flags of the application, of the flagstructs of its commands and
+// of its subcommands to the definitions.
+func collectFlagDefinitions(app interface{}, appname string, definitions *[]flagDefinition, visited map[interface{}]bool) {
+	if reflect.ValueOf(app).Kind() == reflect.Ptr {
+		if visited[app] {
+			return
+		}
+		visited[app] = true
+	}
+
+	quiet := New()
+	quiet.UsageOutput = ioutil.Discard
+	add := func(flagset *FlagSet, scope string) {
+		if flagset == nil {
+			return
+		}
+		aliases := map[string][]string{}
+		for _, alias := range flagset.aliasNames() {
+			aliases[flagset.aliases[alias].name] = append(aliases[flagset.aliases[alias].name], "-"+alias)
+		}
+		for _, name := range flagset.targetNames() {
+			*definitions = append(*definitions, flagDefinition{name: name, target: flagset.targets[name], aliases: aliases[name], scope: scope})
+		}
+	}
+
+	flagset, _ := quiet.GetFlagSet(app, appname)
+	add(flagset, appname)
+
+	st, valid := utils.DerefType(app)
+	if !valid {
+		return
+	}
+	for i := 0; i < st.NumField(); i++ {
+		alias, ok := st.Field(i).Tag.Lookup(FieldTag)
+		split := strings.SplitN(alias, "=", 2)
+		if !ok || len(split) != 2 {
+			continue
+		}
+
+		directive, _ := parseSubcommandDirective(split[1])
+		if split[0] == FlagStructDirective {
+			flagset, _ := quiet.GetFlagSetWithCommand(app, appname, directive.cmd)
+			add(flagset, appname+" "+directive.cmd)
+		} else if split[0] == SubcommandDirective && directive.renamed == "" {
+			subapp, _, _ := subCommand(app, directive.cmd)
+			if v := reflect.ValueOf(subapp); subapp != nil && (v.Kind() != reflect.Ptr || !v.IsNil()) {
+				collectFlagDefinitions(subapp, appname+" "+directive.cmd, definitions, visited)
+			}
+		}
+	}
+}
+
+// helpTopicRequest returns the text of the help topic requested by the arguments with
+// "help <topic>", if the application has such a topic and no help command of its own.
+func helpTopicRequest(app interface{}, arguments []string) (string, bool) {
+	provider, ok := app.(HelpTopicsProvider)
+	if !ok || len(arguments) != 2 || arguments[0] != HelpCommand || hasHelpCommand(app) {
+		return "", false
+	}
+	text, found := provider.GetHelpTopics()[arguments[1]]
+	return text, found
+}
+
+// hasHelpCommand returns true if the application implements a help command or subcommand.
+func hasHelpCommand(app interface{}) bool {
+	if found, _ := hasCommand(app, HelpCommand); found {
+		return true
+	}
+	subapp, _, _ := subCommand(app, HelpCommand)
+	return subapp != nil
+}
+
+// flagHelpRequest returns the name of the flag whose help is requested by the arguments, either
+// with "help --flag name" or with "--help=name". Requests are ignored if the application has a
+// help command of its own.
+func flagHelpRequest(app interface{}, arguments []string) (string, bool) {
+	if len(arguments) > 0 && arguments[0] == HelpCommand {
+		if hasHelpCommand(app) {
+			return "", false
+		} else if len(arguments) == 3 && (arguments[1] == "--flag" || arguments[1] == "-flag") {
+			return arguments[2], true
+		} else if len(arguments) == 2 {
+			for _, prefix := range []string{"--flag=", "-flag="} {
+				if strings.HasPrefix(arguments[1], prefix) {
+					return strings.TrimPrefix(arguments[1], prefix), true
+				}
+			}
+		}
+		return "", false
+	}
+
+	for _, arg := range arguments {
+		if arg == "--" {
+			break
+		}
+		for _, prefix := range []string{"--help=", "-help="} {
+			if strings.HasPrefix(arg, prefix) {
+				return strings.TrimPrefix(arg, prefix), true
+			}
+		}
+	}
+	return "", false
+}
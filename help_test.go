@@ -0,0 +1,118 @@
+package commander_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/apourchet/commander"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlagHelp(t *testing.T) {
+	var buf bytes.Buffer
+	cmd := commander.New()
+	cmd.UsageOutput = &buf
+	app := &Application{IntFlag: 10, SubApp: &SubApplication{}, SubApp2: &SubApplication{SubIntFlag: 3}}
+
+	require.NoError(t, cmd.RunCLI(app, []string{"--help=intflag"}))
+	require.Equal(t, `-intflag
+    An int, with a comma in the description and an = in there too
+  Type:       int
+  Default:    10
+  Applies to: myapp
+`, buf.String())
+	require.Equal(t, 0, app.count)
+
+	buf.Reset()
+	require.NoError(t, cmd.RunCLI(app, []string{"help", "--flag", "subintflag"}))
+	require.Equal(t, `-subintflag
+    Another int
+  Type:       int
+  Default:    0
+  Applies to: myapp subapp
+
+-subintflag
+    Another int
+  Type:       int
+  Default:    3
+  Applies to: myapp subapp2
+`, buf.String())
+
+	help, err := cmd.FlagHelp(&Application3{}, "common")
+	require.NoError(t, err)
+	require.Contains(t, help, "Applies to: CLI cmd1, CLI cmd2\n")
+
+	_, err = cmd.FlagHelp(app, "intflg")
+	require.Error(t, err)
+	require.Equal(t, "unknown flag -intflg; did you mean -intflag (flag of myapp)?", err.Error())
+}
+
+type DetailedFlagApplication struct {
+	Port int    `commander:"flag,name=port,short=p,alias=listen,env=COMMANDER_TEST_HELP_PORT,default=8080,min=1,max=65535,required,usage=Listen port"`
+	TLS  bool   `commander:"flag,name=tls,usage=Serve over TLS"`
+	Cert string `commander:"flag,name=cert,requiredif=tls,requiredif=port=443,pattern=^[a-z]+\\.pem$,minlen=5,maxlen=20,usage=Certificate file"`
+}
+
+func (app *DetailedFlagApplication) Serve() {}
+
+func TestFlagHelpDetails(t *testing.T) {
+	cmd := commander.New()
+	app := &DetailedFlagApplication{}
+
+	t.Run("aliases_env_range", func(t *testing.T) {
+		help, err := cmd.FlagHelp(app, "port")
+		require.NoError(t, err)
+		require.Equal(t, `-port
+    Listen port
+  Type:       int
+  Default:    8080
+  Aliases:    -listen, -p
+  Env:        COMMANDER_TEST_HELP_PORT
+  Required:   yes
+  Min:        1
+  Max:        65535
+  Applies to: CLI
+`, help)
+	})
+
+	t.Run("requiredif_text", func(t *testing.T) {
+		help, err := cmd.FlagHelp(app, "cert")
+		require.NoError(t, err)
+		require.Equal(t, `-cert
+    Certificate file
+  Type:       string
+  Default:    ""
+  Required:   when -tls is set or -port is 443
+  Pattern:    ^[a-z]+\.pem$
+  Min length: 5
+  Max length: 20
+  Applies to: CLI
+`, help)
+	})
+
+	t.Run("plain", func(t *testing.T) {
+		help, err := cmd.FlagHelp(app, "tls")
+		require.NoError(t, err)
+		require.Equal(t, "-tls\n    Serve over TLS\n  Type:       bool\n  Default:    false\n  Applies to: CLI\n", help)
+	})
+}
+
+func TestHelpTopics(t *testing.T) {
+	var buf bytes.Buffer
+	cmd := commander.New()
+	cmd.UsageOutput = &buf
+	app := &TopicsApplication{}
+
+	expected := `Usage of CLI:
+
+Topics:
+  config-format  |  The configuration is a JSON object.
+  environment  |  Environment variables read by the application.
+`
+	assertEqualLines(t, expected, cmd.Usage(app))
+
+	require.NoError(t, cmd.RunCLI(app, []string{"help", "environment"}))
+	require.Equal(t, "Environment variables read by the application.\n\nHOME is where the config lives.\n", buf.String())
+
+	require.Error(t, cmd.RunCLI(app, []string{"help", "unknown"}))
+}
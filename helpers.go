@@ -1,10 +1,18 @@
 package commander
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/apourchet/commander/utils"
 	"github.com/pkg/errors"
@@ -32,26 +40,76 @@ func derefFlagStruct(app interface{}, st reflect.Type, field reflect.StructField
 	if !fieldval.IsValid() {
 		return nil, fmt.Errorf("failed to get flags from field %v of type %v", field.Name, st.Name())
 	}
-	fieldIface := fieldval.Interface()
-	if fieldval.Type().Kind() == reflect.Struct {
-		fieldIface = fieldval.Addr().Interface()
+	return addressField(fieldval, field, st)
+}
+
+// addressField returns a pointer to the field if it holds a struct value, so that flags set and
+// methods called on it change the field itself rather than a copy of it. Other fields are returned
+// as they are.
+func addressField(fieldval reflect.Value, field reflect.StructField, st reflect.Type) (interface{}, error) {
+	if fieldval.Kind() != reflect.Struct {
+		return fieldval.Interface(), nil
+	} else if !fieldval.CanAddr() {
+		return nil, fmt.Errorf("field %v of type %v holds a struct value that cannot be changed; "+
+			"pass a pointer to the %v application instead", field.Name, st.Name(), st.Name())
 	}
-	return fieldIface, nil
+	return fieldval.Addr().Interface(), nil
 }
 
 // hasCommand returns true if the application implements a specific command; and false otherwise.
 func hasCommand(app interface{}, cmd string) (bool, error) {
 	cmd = normalizeCommand(cmd)
+	if !isAllowedCommand(app, cmd) {
+		return false, nil
+	}
 	apptype := reflect.TypeOf(app)
 	for i := 0; i < apptype.NumMethod(); i++ {
-		method := apptype.Method(i)
-		if strings.ToLower(method.Name) == cmd {
+		if commandName(app, apptype.Method(i)) == cmd {
 			return true, nil
 		}
 	}
 	return false, nil
 }
 
+// commandName returns the normalized name of the command that calls the method given.
+func commandName(app interface{}, method reflect.Method) string {
+	if provider, ok := app.(CommandNamesProvider); ok {
+		if name, found := provider.CommandNames()[method.Name]; found {
+			return normalizeCommand(name)
+		}
+	}
+	return strings.ToLower(method.Name)
+}
+
+// commandNames returns the names of the commands that the methods of the application implement,
+// leaving out the methods of the interfaces of this package.
+func commandNames(app interface{}) []string {
+	names := []string{}
+	apptype := reflect.TypeOf(app)
+	for i := 0; apptype != nil && i < apptype.NumMethod(); i++ {
+		method := apptype.Method(i)
+		if cmd := commandName(app, method); !interfaceMethods[method.Name] && isAllowedCommand(app, cmd) {
+			names = append(names, cmd)
+		}
+	}
+	return names
+}
+
+// isAllowedCommand returns false if the application restricts its commands with an
+// AllowedCommandsProvider that does not list the command.
+func isAllowedCommand(app interface{}, cmd string) bool {
+	provider, ok := app.(AllowedCommandsProvider)
+	if !ok {
+		return true
+	}
+	for _, allowed := range provider.GetAllowedCommands() {
+		if normalizeCommand(allowed) == normalizeCommand(cmd) {
+			return true
+		}
+	}
+	return false
+}
+
 func findCommand(app interface{}, commands []string) (string, error) {
 	for _, cmd := range commands {
 		if found, err := hasCommand(app, cmd); err != nil {
@@ -63,26 +121,428 @@ func findCommand(app interface{}, commands []string) (string, error) {
 	return "", nil
 }
 
-// parseSubcommandDirective parses the subcommand directive into the subcommand string and its description.
-func parseSubcommandDirective(directive string) (cmd string, description string) {
-	split := strings.SplitN(directive, ",", 2)
-	if len(split) == 2 {
-		return split[0], split[1]
+// subcommandDirective is the parsed form of a subcommand directive. The format of a subcommand
+// directive is <name>,<description> followed by any of the options ",timeout=<duration>",
+// ",deprecated", ",renamed=<name>" and ",order=<n>".
+type subcommandDirective struct {
+	cmd         string
+	description string
+	timeout     time.Duration
+
+	// order is the position of the subcommand in the usage, if it is not listed in the default order.
+	order int
+
+	// deprecated marks a subcommand that still works but warns when used, and renamed the new name
+	// of a subcommand, which the old name dispatches to.
+	deprecated bool
+	renamed    string
+}
+
+// parseSubcommandDirective parses the subcommand directive into the subcommand string, its
+// description and its options. The command and description are always filled in, even if one of the
+// options is malformed.
+func parseSubcommandDirective(directive string) (subcommandDirective, error) {
+	var parsed subcommandDirective
+	var timeout, order string
+	var hasTimeout, hasOrder bool
+	for {
+		if rest, value, found := cutDirectiveOption(directive, "timeout"); found {
+			directive, timeout, hasTimeout = rest, value, true
+		} else if rest, value, found := cutDirectiveOption(directive, "order"); found {
+			directive, order, hasOrder = rest, value, true
+		} else if rest, value, found := cutDirectiveOption(directive, "renamed"); found {
+			directive, parsed.renamed = rest, value
+		} else if rest, last := cutLastDirectivePart(directive); last == "deprecated" {
+			directive, parsed.deprecated = rest, true
+		} else {
+			break
+		}
+	}
+
+	parsed.cmd, parsed.description = cutFirstDirectivePart(directive)
+
+	if hasTimeout {
+		dur, err := time.ParseDuration(timeout)
+		if err != nil {
+			return parsed, errors.Wrapf(err, "malformed timeout on subcommand %v", parsed.cmd)
+		}
+		parsed.timeout = dur
+	}
+	if hasOrder {
+		n, err := strconv.Atoi(order)
+		if err != nil || n <= 0 {
+			return parsed, errors.Errorf("malformed order on subcommand %v: expected a positive number, got %q", parsed.cmd, order)
+		}
+		parsed.order = n
+	}
+	return parsed, nil
+}
+
+// warning returns the warning to print when the subcommand is used, if any.
+func (directive subcommandDirective) warning(cmd string) string {
+	if directive.renamed != "" {
+		return fmt.Sprintf("warning: subcommand %v was renamed to %v\n", cmd, directive.renamed)
+	} else if directive.deprecated {
+		return fmt.Sprintf("warning: subcommand %v is deprecated\n", cmd)
+	}
+	return ""
+}
+
+// usageDescription returns the description of the subcommand in the usage, with its deprecation.
+func (directive subcommandDirective) usageDescription() string {
+	var marker string
+	if directive.renamed != "" {
+		marker = "renamed to " + directive.renamed
+	} else if directive.deprecated {
+		marker = "deprecated"
+	} else {
+		return directive.description
+	}
+
+	if directive.description == "" {
+		return strings.ToUpper(marker[:1]) + marker[1:]
+	}
+	return directive.description + " (" + marker + ")"
+}
+
+// cutDirectiveOption removes the trailing ",<key>=<value>" option from the directive and returns
+// the value of that option.
+func cutDirectiveOption(directive string, key string) (rest string, value string, found bool) {
+	rest, last := cutLastDirectivePart(directive)
+	if rest == directive || !strings.HasPrefix(last, key+"=") {
+		return directive, "", false
+	}
+	return rest, unquoteDirective(last[len(key)+1:]), true
+}
+
+// cutFirstDirectivePart splits the directive on its first comma, which is neither quoted nor escaped,
+// and unquotes both parts.
+func cutFirstDirectivePart(directive string) (first string, rest string) {
+	parts := splitDirective(directive)
+	if len(parts) == 1 {
+		return unquoteDirective(directive), ""
+	}
+	return unquoteDirective(parts[0]), unquoteDirective(directive[len(parts[0])+1:])
+}
+
+// cutLastDirectivePart splits the directive on its last comma, which is neither quoted nor escaped.
+// The last part is returned as it is written in the directive, and the directive is returned
+// unchanged if it has a single part.
+func cutLastDirectivePart(directive string) (rest string, last string) {
+	parts := splitDirective(directive)
+	if len(parts) == 1 {
+		return directive, directive
+	}
+	last = parts[len(parts)-1]
+	return directive[:len(directive)-len(last)-1], last
+}
+
+// splitDirective splits the directive on the commas that are neither quoted nor escaped. The parts
+// are returned as they are written in the directive.
+func splitDirective(directive string) []string {
+	parts := []string{}
+	start := 0
+	scanDirective(directive, func(i int, literal bool) {
+		if !literal && directive[i] == ',' {
+			parts = append(parts, directive[start:i])
+			start = i + 1
+		}
+	})
+	return append(parts, directive[start:])
+}
+
+// unquoteDirective removes the quotes and the escapes from the part of a directive.
+func unquoteDirective(part string) string {
+	var buf strings.Builder
+	scanDirective(part, func(i int, literal bool) {
+		buf.WriteByte(part[i])
+	})
+	return buf.String()
+}
+
+// unquotePattern removes the single quotes around the pattern of a flag directive, if it has some.
+// Unlike the other parts of a directive, its backslashes are kept, so that they can escape the
+// characters of the regular expression.
+func unquotePattern(part string) string {
+	if len(part) >= 2 && strings.HasPrefix(part, "'") && strings.HasSuffix(part, "'") {
+		return part[1 : len(part)-1]
+	}
+	return part
+}
+
+// scanDirective calls visit with the index of each character of the directive that is neither a
+// quote nor an escape, and whether that character is to be taken literally. A backslash escapes the
+// character that follows it, and single quotes at the start of a value, or after a comma or an
+// equal sign, quote everything until the next single quote. This lets descriptions, usages and
+// values hold commas and equal signs.
+func scanDirective(directive string, visit func(i int, literal bool)) {
+	quoted, escaped, start := false, false, true
+	for i := 0; i < len(directive); i++ {
+		c := directive[i]
+		switch {
+		case escaped:
+			escaped, start = false, false
+			visit(i, true)
+		case c == '\\':
+			escaped = true
+		case c == '\'' && (quoted || start):
+			quoted = !quoted
+		default:
+			start = !quoted && (c == ',' || c == '=')
+			visit(i, quoted)
+		}
+	}
+}
+
+// translateSlashFlags rewrites the "/flag value" and "/flag:value" arguments into "--flag value"
+// and "--flag=value" for the flags that are defined in the flagset, and "/?" into "--help". Like the
+// flag package, it stops at the first argument that is not a flag, or at "--", so that the
+// positional arguments and the arguments of the subcommands are left untouched.
+func translateSlashFlags(flagset *FlagSet, arguments []string) []string {
+	translated := make([]string, len(arguments))
+	copy(translated, arguments)
+	for i := 0; i < len(arguments); i++ {
+		arg := arguments[i]
+		if arg == "--" {
+			break
+		} else if arg == "/?" {
+			translated[i] = "--help"
+			continue
+		}
+
+		var split []string
+		if strings.HasPrefix(arg, "/") {
+			split = strings.SplitN(arg[1:], ":", 2)
+			if flagset.Lookup(split[0]) == nil {
+				break
+			} else if len(split) == 2 {
+				translated[i] = "--" + split[0] + "=" + split[1]
+			} else {
+				translated[i] = "--" + split[0]
+			}
+		} else if strings.HasPrefix(arg, "-") && arg != "-" {
+			split = strings.SplitN(strings.TrimLeft(arg, "-"), "=", 2)
+		} else {
+			break
+		}
+		if f := flagset.Lookup(split[0]); f != nil && len(split) == 1 && !isBoolFlag(f) {
+			i++
+		}
+	}
+	return translated
+}
+
+// expandFlagPrefixes rewrites the flags of the arguments that are given by a prefix of their name,
+// like "--store" for "--store-location", into their full name. Prefixes that several flags of the
+// set start with fail with an error naming those flags, in order. The aliases of a flag count as
+// that flag. Like the flag package, it stops at the first argument that is not a flag, or at "--",
+// leaving the arguments of the subcommands to the flagsets of their own level.
+func expandFlagPrefixes(flagset *FlagSet, arguments []string) ([]string, error) {
+	expanded := make([]string, len(arguments))
+	copy(expanded, arguments)
+	for i := 0; i < len(arguments); i++ {
+		arg := arguments[i]
+		if arg == "--" || !strings.HasPrefix(arg, "-") || strings.TrimLeft(arg, "-") == "" {
+			break
+		}
+		dashes := arg[:len(arg)-len(strings.TrimLeft(arg, "-"))]
+		split := strings.SplitN(arg[len(dashes):], "=", 2)
+		name, hasValue := split[0], len(split) == 2
+		if f := flagset.Lookup(name); f != nil {
+			if !hasValue && !isBoolFlag(f) {
+				i++
+			}
+			continue
+		}
+
+		matches, names := map[string]*flag.Flag{}, []string{}
+		flagset.VisitAll(func(f *flag.Flag) {
+			if !strings.HasPrefix(f.Name, name) {
+				return
+			}
+			canonical := f.Name
+			if alias, ok := f.Value.(*flagAlias); ok {
+				canonical = alias.name
+			}
+			if matches[canonical] == nil {
+				matches[canonical] = flagset.Lookup(canonical)
+				names = append(names, dashes+canonical)
+			}
+		})
+		sort.Strings(names)
+		if len(names) > 1 {
+			return nil, UsageError{
+				Command:   flagset.Name(),
+				Arguments: arguments,
+				Token:     arg,
+				Cause:     fmt.Sprintf("ambiguous flag %v", dashes+name),
+				Hint:      fmt.Sprintf("it could be any of %v", strings.Join(names, ", ")),
+			}
+		} else if len(names) == 1 {
+			expanded[i] = names[0]
+			if hasValue {
+				expanded[i] += "=" + split[1]
+			} else if !isBoolFlag(matches[strings.TrimLeft(names[0], "-")]) {
+				i++
+			}
+		}
+	}
+	return expanded, nil
+}
+
+// isBoolFlag returns true if the flag can be given without a value.
+func isBoolFlag(f *flag.Flag) bool {
+	boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && boolFlag.IsBoolFlag()
+}
+
+// replaceStdinArgument replaces the argument that stands for stdin with the contents of the reader.
+// Trailing newlines are removed from those contents, like shell command substitution does.
+func replaceStdinArgument(args []string, stdin io.Reader) ([]string, error) {
+	replaced := make([]string, len(args))
+	read := false
+	for i, arg := range args {
+		replaced[i] = arg
+		if arg != StdinArgumentName {
+			continue
+		} else if read {
+			return nil, fmt.Errorf("stdin can only be used for one argument")
+		} else if stdin == nil {
+			return nil, fmt.Errorf("no stdin to read argument from")
+		}
+
+		content, err := ioutil.ReadAll(stdin)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read argument from stdin")
+		}
+		replaced[i] = strings.TrimRight(string(content), "\r\n")
+		read = true
+	}
+	return replaced, nil
+}
+
+// resolveNamedArguments orders the arguments given as name=value pairs into the positional order of
+// the parameters of the command. The arguments are returned unchanged if the application does not
+// name the parameters of that command, or if any of them is not a known name=value pair.
+func resolveNamedArguments(app interface{}, cmd string, args []string) ([]string, error) {
+	provider, ok := app.(ArgumentNamesProvider)
+	if !ok || len(args) == 0 {
+		return args, nil
+	}
+	names := provider.GetArgumentNames(cmd)
+
+	values := map[string]string{}
+	for _, arg := range args {
+		split := strings.SplitN(arg, "=", 2)
+		if len(split) != 2 || !containsString(names, split[0]) {
+			return args, nil
+		} else if _, found := values[split[0]]; found {
+			return nil, fmt.Errorf("argument %v given twice", split[0])
+		}
+		values[split[0]] = split[1]
+	}
+
+	resolved := []string{}
+	for _, name := range names {
+		value, found := values[name]
+		if !found {
+			return nil, fmt.Errorf("missing argument %v", name)
+		}
+		resolved = append(resolved, value)
+	}
+	return resolved, nil
+}
+
+// checkArgumentOrder returns an error if one of the arguments of the command is the name of a flag
+// in one of the maps given. Arguments after a "--" terminator are never considered flags.
+func checkArgumentOrder(cmd string, args []string, flags ...map[string]string) error {
+	for _, arg := range args {
+		if arg == "--" {
+			return nil
+		} else if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name := strings.SplitN(strings.TrimLeft(arg, "-"), "=", 2)[0]
+		for _, level := range flags {
+			if _, found := level[name]; found {
+				return fmt.Errorf("place --%v before the arguments of command %v", name, cmd)
+			}
+		}
+	}
+	return nil
+}
+
+// validateStructs calls Validate on each of the structs that implements Validator, and returns
+// all the errors that they return as one.
+func validateStructs(structs []interface{}) error {
+	seen := map[interface{}]bool{}
+	messages := []string{}
+	for _, obj := range structs {
+		if reflect.ValueOf(obj).Kind() == reflect.Ptr {
+			if seen[obj] {
+				continue
+			}
+			seen[obj] = true
+		}
+		if validator, ok := obj.(Validator); ok {
+			if err := validator.Validate(); err != nil {
+				messages = append(messages, err.Error())
+			}
+		}
 	}
-	return split[0], ""
+	if len(messages) > 0 {
+		return fmt.Errorf("invalid flags: %v", strings.Join(messages, "; "))
+	}
+	return nil
+}
+
+// preDispatch calls the PreDispatch hook of the root application, if it has one, with copies of the
+// command path and the arguments of the command about to run.
+func preDispatch(root interface{}, path []string, args []string) error {
+	if hook, ok := root.(PreDispatchHook); ok {
+		return hook.PreDispatch(append([]string{}, path...), append([]string{}, args...))
+	}
+	return nil
 }
 
-func executeHook(app interface{}) error {
+// setCommandPath gives a copy of the command path to the application if it is a
+// CommandPathReceiver.
+func setCommandPath(app interface{}, path []string) {
+	if receiver, ok := app.(CommandPathReceiver); ok {
+		receiver.SetCommandPath(append([]string{}, path...))
+	}
+}
+
+func containsString(list []string, str string) bool {
+	for _, elem := range list {
+		if elem == str {
+			return true
+		}
+	}
+	return false
+}
+
+func executeHook(ctx context.Context, app interface{}) error {
 	if hook, ok := app.(PostFlagParseHook); ok {
 		if err := hook.PostFlagParse(); err != nil {
 			return errors.WithStack(err)
 		}
 	}
+	if hook, ok := app.(PostFlagParseContextHook); ok {
+		if err := hook.PostFlagParseContext(ctx); err != nil {
+			return errors.WithStack(err)
+		}
+	}
 	return nil
 }
 
+// getCLIName returns the name of the application followed by the commands given. The name of an
+// application that does not implement NamedCLI is the name of the running binary.
 func getCLIName(app interface{}, commands ...string) string {
 	appname := "CLI"
+	if len(os.Args) > 0 && os.Args[0] != "" {
+		appname = filepath.Base(os.Args[0])
+	}
 	if casted, ok := app.(NamedCLI); ok {
 		appname = casted.CLIName()
 	}
@@ -92,6 +552,14 @@ func getCLIName(app interface{}, commands ...string) string {
 	return appname
 }
 
+// typeName returns the name of the struct type of the application, for error messages.
+func typeName(app interface{}) string {
+	if st, valid := utils.DerefType(app); valid {
+		return st.Name()
+	}
+	return fmt.Sprintf("%T", app)
+}
+
 func normalizeCommand(cmd string) string {
 	cmd = strings.Replace(cmd, "-", "", -1)
 	cmd = strings.Replace(cmd, "_", "", -1)
@@ -99,12 +567,20 @@ func normalizeCommand(cmd string) string {
 	return cmd
 }
 
+// normalizeCommandPath normalizes each command of the space-separated command path.
+func normalizeCommandPath(path string) string {
+	return normalizeCommand(strings.Join(strings.Fields(path), " "))
+}
+
 func getMethod(app interface{}, cmd string) (reflect.Method, error) {
 	apptype := reflect.TypeOf(app)
 	var method reflect.Method
+	if !isAllowedCommand(app, cmd) {
+		return method, fmt.Errorf("command %v is not allowed", cmd)
+	}
 	for i := 0; i < apptype.NumMethod(); i++ {
 		method = apptype.Method(i)
-		if strings.ToLower(method.Name) == normalizeCommand(cmd) {
+		if commandName(app, method) == normalizeCommand(cmd) {
 			return method, nil
 		}
 	}
@@ -0,0 +1,136 @@
+package commander
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// HistoryCommand is the name of the command that the Commander registers on the application when
+// it records the history of its invocations.
+const HistoryCommand = "history"
+
+// History records every invocation of an application into a file, one JSON object per line.
+type History struct {
+	// Path is the file that the invocations are appended to.
+	Path string
+
+	// Redacted lists the names of the flags whose values should never be written to the file, on
+	// top of the flags with the secret option.
+	Redacted []string
+}
+
+// HistoryEntry is a single invocation of the application recorded by the History.
+type HistoryEntry struct {
+	Time      time.Time `json:"time"`
+	Path      []string  `json:"path"`
+	Arguments []string  `json:"arguments"`
+
+	// Status is the exit code of the invocation, as given by ExitCode.
+	Status int `json:"status"`
+
+	// Redacted is true when the values of some flags were left out of the arguments, in which
+	// case the invocation cannot be re-run.
+	Redacted bool `json:"redacted,omitempty"`
+}
+
+// NewHistory returns a History that records the invocations of the application in the user
+// config directory, under <appname>/history.
+func NewHistory(appname string) (*History, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find user config directory")
+	}
+	return &History{Path: filepath.Join(dir, appname, "history")}, nil
+}
+
+// Record appends the invocation to the history file. The arguments of the entry are written as
+// they are: the Commander redacts them before it records its invocations.
+func (history *History) Record(entry HistoryEntry) error {
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return errors.Wrap(err, "failed to serialize history entry")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(history.Path), 0700); err != nil {
+		return errors.Wrap(err, "failed to create history directory")
+	}
+	file, err := os.OpenFile(history.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.Wrap(err, "failed to open history file")
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(content, '\n'))
+	return errors.Wrap(err, "failed to write history entry")
+}
+
+// Entries returns all the invocations recorded in the history file, oldest first.
+func (history *History) Entries() ([]HistoryEntry, error) {
+	file, err := os.Open(history.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to open history file")
+	}
+	defer file.Close()
+
+	entries := []HistoryEntry{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		entry := HistoryEntry{}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, errors.Wrap(err, "failed to parse history entry")
+		}
+		entries = append(entries, entry)
+	}
+	return entries, errors.Wrap(scanner.Err(), "failed to read history file")
+}
+
+// runHistory lists the entries of the history of the application, or re-runs one of them if its
+// number is given.
+func (commander Commander) runHistory(ctx context.Context, app interface{}, arguments []string) error {
+	entries, err := commander.History.Entries()
+	if err != nil {
+		return err
+	}
+
+	if len(arguments) == 0 {
+		for i, entry := range entries {
+			fmt.Fprintf(commander.UsageOutput, "%5d  %v  %v  (status: %d)\n", i+1,
+				entry.Time.Format(time.RFC3339), strings.Join(entry.Arguments, " "), entry.Status)
+		}
+		return nil
+	}
+
+	index, err := strconv.Atoi(arguments[0])
+	if err != nil || index < 1 || index > len(entries) {
+		return fmt.Errorf("no history entry %v", arguments[0])
+	}
+	entry := entries[index-1]
+	if entry.Redacted {
+		return fmt.Errorf("cannot re-run history entry %v: it contains redacted flags", index)
+	}
+	return commander.RunCLIContext(ctx, app, entry.Arguments)
+}
+
+// isHistoryCommand returns true if the arguments invoke the history command, and the application
+// does not define a command or subcommand with the same name.
+func isHistoryCommand(app interface{}, arguments []string) bool {
+	if len(arguments) == 0 || arguments[0] != HistoryCommand {
+		return false
+	}
+	if found, _ := hasCommand(app, HistoryCommand); found {
+		return false
+	}
+	subapp, _, _ := subCommand(app, HistoryCommand)
+	return subapp == nil
+}
@@ -0,0 +1,141 @@
+package commander_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/apourchet/commander"
+	"github.com/stretchr/testify/require"
+)
+
+type HistoryApplication struct {
+	Token string `commander:"flag=token,A secret token"`
+	Quiet bool   `commander:"flag=quiet,Print nothing"`
+	count int
+	said  string
+}
+
+func (app *HistoryApplication) Incr() { app.count++ }
+
+func (app *HistoryApplication) Say(word string) { app.said = word }
+
+func (app *HistoryApplication) Fail() error { return errTest }
+
+func (app *HistoryApplication) Exit(code int) int { return code }
+
+type VaultApplication struct {
+	Vault *VaultSubApplication `commander:"subcommand=vault"`
+}
+
+type VaultSubApplication struct {
+	Region string `commander:"flag=region"`
+	Creds  struct {
+		Password string `commander:"flag,name=password,short=p,secret"`
+	} `commander:"flagstruct=login"`
+}
+
+func (app *VaultSubApplication) Login(user string) {}
+
+func TestHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "commander-history")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	buf := &bytes.Buffer{}
+	cmd := commander.New()
+	cmd.UsageOutput = buf
+	cmd.History = &commander.History{
+		Path:     filepath.Join(dir, "app", "history"),
+		Redacted: []string{"token", "quiet"},
+	}
+
+	app := &HistoryApplication{}
+	require.NoError(t, cmd.RunCLI(app, []string{"incr"}))
+	require.Equal(t, errTest, cmd.RunCLI(app, []string{"--token", "secret", "fail"}))
+	require.NoError(t, cmd.RunCLI(app, []string{"--token=secret", "incr"}))
+
+	entries, err := cmd.History.Entries()
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	require.Equal(t, []string{"incr"}, entries[0].Path)
+	require.Equal(t, 0, entries[0].Status)
+	require.Equal(t, []string{"--token", "REDACTED", "fail"}, entries[1].Arguments)
+	require.Equal(t, 1, entries[1].Status)
+	require.True(t, entries[1].Redacted)
+	require.Equal(t, []string{"--token=REDACTED", "incr"}, entries[2].Arguments)
+
+	t.Run("list", func(t *testing.T) {
+		buf.Reset()
+		require.NoError(t, cmd.RunCLI(app, []string{"history"}))
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 3)
+		require.Contains(t, lines[1], "--token REDACTED fail")
+		require.Contains(t, lines[1], "(status: 1)")
+	})
+
+	t.Run("rerun", func(t *testing.T) {
+		require.NoError(t, cmd.RunCLI(app, []string{"history", "1"}))
+		require.Equal(t, 3, app.count)
+
+		require.Error(t, cmd.RunCLI(app, []string{"history", "2"}))
+		require.Error(t, cmd.RunCLI(app, []string{"history", "10"}))
+	})
+
+	t.Run("bool flags", func(t *testing.T) {
+		require.NoError(t, cmd.RunCLI(app, []string{"--quiet", "say", "NOT-REDACTED"}))
+		require.NoError(t, cmd.RunCLI(app, []string{"say", "--", "--token"}))
+		entries, err := cmd.History.Entries()
+		require.NoError(t, err)
+		require.Len(t, entries, 6)
+		require.Equal(t, []string{"--quiet", "say", "NOT-REDACTED"}, entries[4].Arguments)
+		require.False(t, entries[4].Redacted)
+		require.Equal(t, []string{"say", "--", "--token"}, entries[5].Arguments)
+		require.False(t, entries[5].Redacted)
+
+		require.NoError(t, cmd.RunCLI(app, []string{"history", "6"}))
+		require.Equal(t, "--token", app.said)
+	})
+}
+
+func TestHistorySecretFlags(t *testing.T) {
+	dir, err := ioutil.TempDir("", "commander-history")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cmd := commander.New()
+	cmd.History = &commander.History{Path: filepath.Join(dir, "history")}
+	app := &VaultApplication{Vault: &VaultSubApplication{}}
+	require.NoError(t, cmd.RunCLI(app, []string{"vault", "--region", "eu", "login", "--password", "hunter2", "bob"}))
+	require.NoError(t, cmd.RunCLI(app, []string{"vault", "login", "-p=hunter2", "bob"}))
+
+	entries, err := cmd.History.Entries()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, []string{"vault", "--region", "eu", "login", "--password", "REDACTED", "bob"}, entries[0].Arguments)
+	require.Equal(t, []string{"vault", "login", "-p=REDACTED", "bob"}, entries[1].Arguments)
+}
+
+func TestHistoryExitStatus(t *testing.T) {
+	dir, err := ioutil.TempDir("", "commander-history")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cmd := commander.New()
+	cmd.UsageOutput = ioutil.Discard
+	cmd.History = &commander.History{Path: filepath.Join(dir, "history")}
+	app := &HistoryApplication{}
+	require.Error(t, cmd.RunCLI(app, []string{"exit", "3"}))
+	require.NoError(t, cmd.RunCLI(app, []string{"exit", "0"}))
+	require.Error(t, cmd.RunCLI(app, []string{"nothing"}))
+
+	entries, err := cmd.History.Entries()
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	require.Equal(t, 3, entries[0].Status)
+	require.Equal(t, 0, entries[1].Status)
+	require.Equal(t, 1, entries[2].Status)
+}
@@ -0,0 +1,53 @@
+package commander
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/apourchet/commander/utils"
+)
+
+// Provide registers a value to inject into the fields of the application and of its subcommands
+// that are tagged with an InjectDirective of the same name. This is how dependencies that are not
+// flags, like database handles or API clients, reach the nested structs of an application.
+func (commander *Commander) Provide(name string, value interface{}) {
+	if commander.providers == nil {
+		commander.providers = map[string]interface{}{}
+	}
+	commander.providers[name] = value
+}
+
+// inject sets the fields of the application that are tagged with an InjectDirective to the values
+// provided to the Commander.
+func (commander Commander) inject(app interface{}) error {
+	st, valid := utils.DerefType(app)
+	if !valid {
+		return nil
+	}
+	for i := 0; i < st.NumField(); i++ {
+		field := st.Field(i)
+		alias, ok := field.Tag.Lookup(FieldTag)
+		split := strings.SplitN(alias, "=", 2)
+		if !ok || len(split) != 2 || split[0] != InjectDirective {
+			continue
+		}
+
+		provided, found := commander.providers[split[1]]
+		if !found {
+			return fmt.Errorf("no value provided to inject %v into field %v of %v", split[1], field.Name, st.Name())
+		}
+		v, valid := utils.DerefValue(app)
+		if !valid || !v.CanAddr() {
+			return fmt.Errorf("failed to inject %v into field %v of %v: the application needs to be a pointer", split[1], field.Name, st.Name())
+		}
+		value := reflect.ValueOf(provided)
+		if !value.IsValid() {
+			value = reflect.Zero(field.Type)
+		} else if !value.Type().AssignableTo(field.Type) {
+			return fmt.Errorf("failed to inject %v into field %v of %v: %v is not assignable to %v", split[1], field.Name, st.Name(), value.Type(), field.Type)
+		}
+		v.Field(i).Set(value)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package commander_test
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/apourchet/commander"
+	"github.com/stretchr/testify/require"
+)
+
+type Database struct {
+	name string
+}
+
+type InjectedApplication struct {
+	DB *Database `commander:"inject=db"`
+
+	Sub *InjectedSubApplication `commander:"subcommand=sub"`
+}
+
+type InjectedSubApplication struct {
+	DB    *Database `commander:"inject=db"`
+	Label string    `commander:"inject=label"`
+
+	used string
+}
+
+func (app *InjectedSubApplication) Use() { app.used = app.Label + ":" + app.DB.name }
+
+func TestInject(t *testing.T) {
+	db := &Database{name: "main"}
+	cmd := commander.New()
+	cmd.UsageOutput = ioutil.Discard
+	cmd.Provide("db", db)
+
+	app := &InjectedApplication{Sub: &InjectedSubApplication{}}
+	err := cmd.RunCLI(app, []string{"sub", "use"})
+	require.Error(t, err)
+	require.Equal(t, "no value provided to inject label into field Label of InjectedSubApplication", err.Error())
+
+	cmd.Provide("label", "primary")
+	require.NoError(t, cmd.RunCLI(app, []string{"sub", "use"}))
+	require.Equal(t, db, app.DB)
+	require.Equal(t, "primary:main", app.Sub.used)
+	require.NoError(t, commander.Validate(app))
+
+	cmd.Provide("label", 3)
+	require.Error(t, cmd.RunCLI(app, []string{"sub", "use"}))
+}
@@ -0,0 +1,106 @@
+package commander
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/apourchet/commander/utils"
+	"github.com/pkg/errors"
+)
+
+// lockFile returns the path of the lock file that the command takes: the LockFile of the
+// Commander, or else the path of the first LockDirective among the applications given.
+func (commander Commander) lockFile(apps []interface{}) string {
+	if commander.LockFile != "" {
+		return commander.LockFile
+	}
+	for _, app := range apps {
+		st, valid := utils.DerefType(app)
+		if !valid {
+			continue
+		}
+		for i := 0; i < st.NumField(); i++ {
+			split := strings.SplitN(st.Field(i).Tag.Get(FieldTag), "=", 2)
+			if len(split) == 2 && split[0] == LockDirective && split[1] != "" {
+				return split[1]
+			}
+		}
+	}
+	return ""
+}
+
+// acquireLock creates the lock file at the path given, holding the pid of the process, and returns
+// the function that releases it. The file is written aside and then linked into place, so that it
+// never exists without the pid. A lock file left behind by a process that is no longer running is
+// taken over. Nothing is locked if the path is empty.
+func acquireLock(path string) (release func(), err error) {
+	if path == "" {
+		return func() {}, nil
+	}
+	file, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create lock file %v", path)
+	}
+	defer os.Remove(file.Name())
+	_, err = fmt.Fprintf(file, "%d\n", os.Getpid())
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to write lock file %v", path)
+	}
+
+	for attempt := 0; attempt < 3; attempt++ {
+		if err := os.Link(file.Name(), path); err == nil {
+			return func() { os.Remove(path) }, nil
+		} else if !os.IsExist(err) {
+			return nil, errors.Wrapf(err, "failed to create lock file %v", path)
+		}
+
+		content, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "failed to read lock file %v", path)
+		}
+		if pid, err := strconv.Atoi(strings.TrimSpace(string(content))); err == nil && processRunning(pid) {
+			return nil, runningError(pid, path)
+		}
+		if err := takeOverLock(path, file.Name()+".stale", content); err != nil {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("failed to acquire lock file %v", path)
+}
+
+// takeOverLock removes the stale lock file at the path given, which held the content given, so that
+// it can be created again. The file is first moved aside, which only one of the processes taking it
+// over at the same time manages to do. If the file moved aside turns out to be the lock of another
+// process, which took it over since its content was read, it is put back.
+func takeOverLock(path string, aside string, stale []byte) error {
+	if err := os.Rename(path, aside); os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return errors.Wrapf(err, "failed to remove stale lock file %v", path)
+	}
+	defer os.Remove(aside)
+
+	content, err := ioutil.ReadFile(aside)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read lock file %v", path)
+	} else if string(content) == string(stale) {
+		return nil
+	}
+	os.Link(aside, path)
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(content)))
+	return runningError(pid, path)
+}
+
+// runningError is the error returned when the lock file is held by another process.
+func runningError(pid int, path string) error {
+	return fmt.Errorf("another invocation is running (pid %d): remove %v if it is not", pid, path)
+}
@@ -0,0 +1,19 @@
+//go:build !windows
+// +build !windows
+
+package commander
+
+import (
+	"os"
+	"syscall"
+)
+
+// processRunning returns true if a process with the pid given is running.
+func processRunning(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || err == syscall.EPERM
+}
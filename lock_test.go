@@ -0,0 +1,92 @@
+package commander_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apourchet/commander"
+	"github.com/stretchr/testify/require"
+)
+
+type LockedApplication struct {
+	_     struct{} `commander:"lock=/nonexistent/locked.lock"`
+	count int
+}
+
+func (app *LockedApplication) Run() { app.count++ }
+
+func TestLockFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "commander-lock")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.lock")
+
+	cmd := commander.New()
+	cmd.UsageOutput = ioutil.Discard
+	cmd.LockFile = path
+	app := &LockedApplication{}
+	require.NoError(t, cmd.RunCLI(app, []string{"run"}))
+	require.Equal(t, 1, app.count)
+	_, err = os.Stat(path)
+	require.True(t, os.IsNotExist(err))
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(fmt.Sprintf("%d\n", os.Getpid())), 0644))
+	err = cmd.RunCLI(app, []string{"run"})
+	require.EqualError(t, err, fmt.Sprintf("another invocation is running (pid %d): remove %v if it is not", os.Getpid(), path))
+	require.Equal(t, 1, app.count)
+
+	// A lock file whose process is gone is taken over.
+	require.NoError(t, ioutil.WriteFile(path, []byte("999999999\n"), 0644))
+	require.NoError(t, cmd.RunCLI(app, []string{"run"}))
+	require.Equal(t, 2, app.count)
+
+	cmd.LockFile = ""
+	require.Error(t, cmd.RunCLI(app, []string{"run"}))
+	require.Equal(t, 2, app.count)
+}
+
+type BlockingLockedApplication struct {
+	release chan struct{}
+}
+
+func (app *BlockingLockedApplication) Wait() { <-app.release }
+
+func (app *BlockingLockedApplication) Panic() { panic("boom") }
+
+func TestLockFileTakeOver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "commander-lock")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.lock")
+
+	cmd := commander.New()
+	cmd.UsageOutput = ioutil.Discard
+	cmd.LockFile = path
+	require.Panics(t, func() { cmd.RunCLI(&BlockingLockedApplication{}, []string{"panic"}) })
+	_, err = os.Stat(path)
+	require.True(t, os.IsNotExist(err))
+
+	// Only one of the invocations that find the same stale lock file takes it over.
+	require.NoError(t, ioutil.WriteFile(path, []byte("999999999\n"), 0644))
+	app := &BlockingLockedApplication{release: make(chan struct{})}
+	errs := make(chan error)
+	for i := 0; i < 8; i++ {
+		go func() { errs <- cmd.RunCLI(app, []string{"wait"}) }()
+	}
+	for i := 0; i < 7; i++ {
+		select {
+		case err := <-errs:
+			require.Error(t, err)
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "several invocations took the lock file over")
+		}
+	}
+	close(app.release)
+	require.NoError(t, <-errs)
+	_, err = os.Stat(path)
+	require.True(t, os.IsNotExist(err))
+}
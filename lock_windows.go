@@ -0,0 +1,30 @@
+package commander
+
+import (
+	"syscall"
+)
+
+// The access right and the exit code that processRunning needs, which the syscall package does not
+// define.
+const (
+	processQueryLimitedInformation = 0x1000
+	stillActive                    = 259
+)
+
+// processRunning returns true if a process with the pid given is running. Signals cannot be sent to
+// processes on Windows, so the exit code of the process is checked instead.
+func processRunning(pid int) bool {
+	handle, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err == syscall.ERROR_ACCESS_DENIED {
+		return true
+	} else if err != nil {
+		return false
+	}
+	defer syscall.CloseHandle(handle)
+
+	var code uint32
+	if err := syscall.GetExitCodeProcess(handle, &code); err != nil {
+		return true
+	}
+	return code == stillActive
+}
@@ -0,0 +1,89 @@
+package commander
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// LogFileFlagName is the name of the flag added by the Commander when LogFileFlag is set.
+const LogFileFlagName = "log-file"
+
+// LogTimeFormat is the format of the timestamps that start the lines of the log files.
+const LogTimeFormat = time.RFC3339
+
+// logFile copies what the Commander writes to a file, starting each line with a timestamp. It is
+// safe to write to from the several outputs that share it.
+type logFile struct {
+	mu      sync.Mutex
+	file    *os.File
+	now     func() time.Time
+	midline bool
+}
+
+// openLogFile opens the log file at the path given, appending to it if it exists, and logs the
+// arguments of the invocation, which are expected to be redacted already.
+func openLogFile(path string, arguments []string) (*logFile, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open log file")
+	}
+	log := &logFile{file: file, now: time.Now}
+	fmt.Fprintf(log, "invocation: %v\n", ShellJoin(arguments))
+	return log, nil
+}
+
+func (log *logFile) Write(p []byte) (int, error) {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	var buf bytes.Buffer
+	for _, line := range bytes.SplitAfter(p, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		} else if !log.midline {
+			buf.WriteString(log.now().Format(LogTimeFormat) + " ")
+		}
+		buf.Write(line)
+		log.midline = line[len(line)-1] != '\n'
+	}
+	if _, err := log.file.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// close logs the error that the invocation ended with, if any, and closes the file.
+func (log *logFile) close(err error) {
+	if log.midline {
+		fmt.Fprintln(log)
+	}
+	if err != nil {
+		fmt.Fprintf(log, "error: %v\n", err)
+	}
+	log.file.Close()
+}
+
+// withLogFile returns the Commander with its usage output and trace copied to the log file, along
+// with its Stdout if LogCommandOutput is set.
+func (commander Commander) withLogFile(log *logFile) Commander {
+	commander.UsageOutput = teeLog(commander.UsageOutput, log)
+	if commander.Trace != nil {
+		commander.Trace = teeLog(commander.Trace, log)
+	}
+	if commander.LogCommandOutput {
+		commander.Stdout = teeLog(commander.Stdout, log)
+	}
+	return commander
+}
+
+func teeLog(w io.Writer, log *logFile) io.Writer {
+	if w == nil {
+		return log
+	}
+	return io.MultiWriter(w, log)
+}
@@ -0,0 +1,89 @@
+package commander_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/apourchet/commander"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "commander-log")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "ops.log")
+
+	var out bytes.Buffer
+	cmd := commander.New()
+	cmd.LogFileFlag = true
+	cmd.LogCommandOutput = true
+	cmd.Stdin = strings.NewReader("abc")
+	cmd.Stdout = &out
+	require.NoError(t, cmd.RunCLI(&StreamApplication{}, []string{"--log-file", path, "upper", "x"}))
+	require.Equal(t, "xABC", out.String())
+
+	cmd.LogCommandOutput = false
+	cmd.UsageOutput = ioutil.Discard
+	require.Error(t, cmd.RunCLI(&Application{}, []string{"--log-file=" + path, "opthree"}))
+
+	content, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	stamp := `\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\S* `
+	expected := regexp.MustCompile(`^` +
+		stamp + `invocation: --log-file \S+ upper x\n` +
+		stamp + `xABC\n` +
+		stamp + `invocation: --log-file=\S+ opthree\n` +
+		stamp + `error: ERROR\n$`)
+	require.Regexp(t, expected, string(content))
+}
+
+func TestLogFileUsage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "commander-log")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "ops.log")
+
+	var usage bytes.Buffer
+	cmd := commander.New()
+	cmd.LogFileFlag = true
+	cmd.UsageOutput = &usage
+	require.Error(t, cmd.RunCLI(&Application{}, []string{"--log-file", path, "nothing"}))
+
+	content, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.NotEmpty(t, usage.String())
+	for _, line := range strings.Split(strings.TrimSpace(usage.String()), "\n") {
+		require.Contains(t, string(content), line)
+	}
+	require.Contains(t, string(content), "error: ")
+}
+
+func TestLogFileSecretFlags(t *testing.T) {
+	dir, err := ioutil.TempDir("", "commander-log")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "ops.log")
+
+	cmd := commander.New()
+	cmd.LogFileFlag = true
+	app := &VaultApplication{Vault: &VaultSubApplication{}}
+	require.NoError(t, cmd.RunCLI(app, []string{"--log-file", path, "vault", "login", "--password", "hunter2", "bob"}))
+	require.Equal(t, "hunter2", app.Vault.Creds.Password)
+
+	content, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(content), "invocation: --log-file "+path+" vault login --password REDACTED bob\n")
+	require.NotContains(t, string(content), "hunter2")
+
+	cmd.History = &commander.History{Path: filepath.Join(dir, "history"), Redacted: []string{"region"}}
+	require.NoError(t, cmd.RunCLI(app, []string{"--log-file=" + path, "vault", "--region=eu", "login", "bob"}))
+	content, err = ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(content), "invocation: --log-file="+path+" vault --region=REDACTED login bob\n")
+}
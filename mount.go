@@ -0,0 +1,126 @@
+package commander
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var mounts = struct {
+	sync.RWMutex
+	children map[interface{}]map[string]interface{}
+}{children: map[interface{}]map[string]interface{}{}}
+
+// mountGroup is the subcommand created for the intermediate elements of a mount path that do not
+// correspond to an existing subcommand.
+type mountGroup struct {
+	name string
+}
+
+// Mount grafts the sub-application under the path of commands given, with the commands separated by
+// slashes. Elements of the path that are already subcommands of the application are followed, and
+// the others are created as groups that only contain the commands mounted under them. The
+// application needs to be a pointer so that its mounts can be found when it runs. Mounts are kept
+// for the whole process, by every Commander, until they are removed with Unmount.
+func Mount(app interface{}, path string, subapp interface{}) error {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if segments[0] == "" {
+		return fmt.Errorf("cannot mount on an empty path")
+	} else if reflect.ValueOf(app).Kind() != reflect.Ptr {
+		return fmt.Errorf("can only mount on pointers to applications, got %T", app)
+	}
+
+	host := app
+	for _, segment := range segments[:len(segments)-1] {
+		if child, _, err := subCommand(host, segment); err != nil {
+			return err
+		} else if child != nil {
+			host = child
+			continue
+		}
+		group := &mountGroup{name: segment}
+		addMount(host, segment, group)
+		host = group
+	}
+
+	last := segments[len(segments)-1]
+	if child, _, err := subCommand(host, last); err != nil {
+		return err
+	} else if child != nil {
+		return fmt.Errorf("cannot mount on %v: subcommand already exists", path)
+	}
+	addMount(host, last, subapp)
+	return nil
+}
+
+// Unmount removes the sub-application mounted under the path given by Mount, along with the groups
+// of the path that it leaves empty. It fails if nothing is mounted under that path.
+func Unmount(app interface{}, path string) error {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	hosts := []interface{}{app}
+	for _, segment := range segments[:len(segments)-1] {
+		child, _, err := subCommand(hosts[len(hosts)-1], segment)
+		if err != nil {
+			return err
+		} else if child == nil {
+			return fmt.Errorf("nothing is mounted on %v", path)
+		}
+		hosts = append(hosts, child)
+	}
+
+	mounts.Lock()
+	defer mounts.Unlock()
+	last := segments[len(segments)-1]
+	if _, found := mounts.children[hosts[len(hosts)-1]][last]; !found {
+		return fmt.Errorf("nothing is mounted on %v", path)
+	}
+	delete(mounts.children[hosts[len(hosts)-1]], last)
+	for i := len(hosts) - 1; i >= 0; i-- {
+		if len(mounts.children[hosts[i]]) > 0 {
+			break
+		}
+		delete(mounts.children, hosts[i])
+		if _, isGroup := hosts[i].(*mountGroup); !isGroup || i == 0 {
+			break
+		}
+		delete(mounts.children[hosts[i-1]], segments[i-1])
+	}
+	return nil
+}
+
+func addMount(host interface{}, name string, subapp interface{}) {
+	mounts.Lock()
+	defer mounts.Unlock()
+	if mounts.children[host] == nil {
+		mounts.children[host] = map[string]interface{}{}
+	}
+	mounts.children[host][name] = subapp
+}
+
+// mountedSubcommand returns the sub-application mounted on the application under the name given, or
+// nil if there is none.
+func mountedSubcommand(app interface{}, name string) interface{} {
+	if !reflect.TypeOf(app).Comparable() {
+		return nil
+	}
+	mounts.RLock()
+	defer mounts.RUnlock()
+	return mounts.children[app][name]
+}
+
+// mountedNames returns the sorted names of the sub-applications mounted on the application.
+func mountedNames(app interface{}) []string {
+	if !reflect.TypeOf(app).Comparable() {
+		return nil
+	}
+	mounts.RLock()
+	defer mounts.RUnlock()
+	names := []string{}
+	for name := range mounts.children[app] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
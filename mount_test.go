@@ -0,0 +1,63 @@
+package commander_test
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/apourchet/commander"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMount(t *testing.T) {
+	app := &Application{SubApp: &SubApplication{}}
+	db := &SubSubApplication{}
+	extra := &SubSubApplication{}
+	require.NoError(t, commander.Mount(app, "tools/db", db))
+	require.NoError(t, commander.Mount(app, "subapp/extra", extra))
+
+	t.Run("dispatch", func(t *testing.T) {
+		require.NoError(t, commander.New().RunCLI(app, []string{"tools", "db", "opdeep"}))
+		require.Equal(t, 1, db.count)
+
+		require.NoError(t, commander.New().RunCLI(app, []string{"--intflag", "10", "subapp", "extra", "opdeep"}))
+		require.Equal(t, 1, extra.count)
+		require.Equal(t, 10, app.IntFlag)
+	})
+
+	t.Run("usage", func(t *testing.T) {
+		app.IntFlag = 10
+		expected := `Usage of myapp:
+  -intflag
+    	An int, with a comma in the description and an = in there too (type: int, default: 10)
+
+Sub-Commands:
+  subapp  |  Use subapp commands
+  subapp2  |  Use subapp commands
+  tools  |  No description for this subcommand
+`
+		assertEqualLines(t, expected, commander.New().Usage(app))
+		require.Contains(t, commander.New().NamedUsage(app.SubApp, "myapp subapp"), "extra  |")
+	})
+
+	t.Run("conflicts", func(t *testing.T) {
+		require.Error(t, commander.Mount(app, "tools/db", &SubSubApplication{}))
+		require.Error(t, commander.Mount(app, "subapp", &SubSubApplication{}))
+		require.Error(t, commander.Mount(app, "", &SubSubApplication{}))
+		require.Error(t, commander.Mount(Application{}, "tools", &SubSubApplication{}))
+	})
+
+	t.Run("unmount", func(t *testing.T) {
+		require.NoError(t, commander.Unmount(app, "tools/db"))
+		require.Error(t, commander.Unmount(app, "tools/db"))
+		cmd := commander.New()
+		cmd.UsageOutput = ioutil.Discard
+		require.Error(t, cmd.RunCLI(app, []string{"tools", "db", "opdeep"}))
+		require.NotContains(t, commander.New().Usage(app), "tools")
+
+		require.NoError(t, commander.Unmount(app, "subapp/extra"))
+		require.NotContains(t, commander.New().NamedUsage(app.SubApp, "myapp subapp"), "extra  |")
+		require.Error(t, commander.Unmount(app, "subapp"))
+		require.NoError(t, commander.Mount(app, "tools/db", db))
+		require.NoError(t, commander.Unmount(app, "tools/db"))
+	})
+}
@@ -0,0 +1,39 @@
+package commander
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// MultiCall runs one of the applications given with the command line arguments of the process,
+// choosing it from the name of the binary that was invoked. This lets one binary be installed under
+// several names, like busybox does.
+func MultiCall(apps map[string]interface{}) error {
+	return New().RunMultiCall(apps, os.Args)
+}
+
+// RunMultiCall runs the application whose name is the base name of argv[0] with the rest of argv.
+// The extension of the binary is ignored if no application has the full name.
+func (commander Commander) RunMultiCall(apps map[string]interface{}, argv []string) error {
+	if len(argv) == 0 {
+		return fmt.Errorf("no binary name to choose an application from")
+	}
+
+	name := filepath.Base(argv[0])
+	app, found := apps[name]
+	if !found {
+		app, found = apps[strings.TrimSuffix(name, filepath.Ext(name))]
+	}
+	if !found {
+		names := []string{}
+		for name := range apps {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown application %v, expected one of: %v", name, strings.Join(names, ", "))
+	}
+	return commander.RunCLI(app, argv[1:])
+}
@@ -0,0 +1,29 @@
+package commander_test
+
+import (
+	"testing"
+
+	"github.com/apourchet/commander"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunMultiCall(t *testing.T) {
+	app := &Application{}
+	copier := &CopyApplication{}
+	apps := map[string]interface{}{
+		"myapp": app,
+		"cp":    copier,
+	}
+
+	require.NoError(t, commander.New().RunMultiCall(apps, []string{"/usr/bin/myapp", "opone", "test"}))
+	require.Equal(t, 1, app.count)
+
+	require.NoError(t, commander.New().RunMultiCall(apps, []string{`cp.exe`, "copy", "a", "b"}))
+	require.Equal(t, "b", copier.dst)
+
+	err := commander.New().RunMultiCall(apps, []string{"/bin/mv", "a", "b"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "expected one of: cp, myapp")
+
+	require.Error(t, commander.New().RunMultiCall(apps, nil))
+}
@@ -0,0 +1,95 @@
+package commander
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// PluginFlagsEnv is the environment variable through which external plugins receive the flags that
+// were given to their parent commands, serialized as a JSON object of flag names to values.
+const PluginFlagsEnv = "COMMANDER_FLAGS"
+
+// pluginName returns the name of the executable that implements the command cmd as an external
+// plugin of the application, in the form <app>-<subcommands...>-<cmd>.
+func pluginName(appname string, cmd string) string {
+	return strings.Join(append(strings.Fields(appname), cmd), "-")
+}
+
+// runExternalPlugin looks for an executable implementing the command on the PATH and runs it with
+// the arguments given, connected to the Stdin and Stdout of the Commander and with its standard
+// error going to the UsageOutput. It returns false if no such executable exists.
+func (commander Commander) runExternalPlugin(appname string, cmd string, args []string, flags map[string]string) (bool, error) {
+	path, err := exec.LookPath(pluginName(appname, cmd))
+	if err != nil {
+		return false, nil
+	}
+
+	serialized, err := json.Marshal(flags)
+	if err != nil {
+		return true, errors.Wrap(err, "failed to serialize flags for plugin")
+	}
+
+	plugin := exec.Command(path, args...)
+	plugin.Stdin, plugin.Stdout, plugin.Stderr = commander.Stdin, commander.Stdout, commander.UsageOutput
+	plugin.Env = append(os.Environ(), PluginFlagsEnv+"="+string(serialized))
+	return true, plugin.Run()
+}
+
+var plugins = struct {
+	sync.RWMutex
+	factories map[string]func() interface{}
+}{factories: map[string]func() interface{}{}}
+
+// RegisterPlugin makes a subcommand available to every application that has a field with the
+// plugins directive. The factory creates the subcommand struct each time the plugin is invoked.
+// Registering the same plugin name twice panics.
+func RegisterPlugin(name string, factory func() interface{}) {
+	plugins.Lock()
+	defer plugins.Unlock()
+	if factory == nil {
+		panic("commander: RegisterPlugin factory is nil")
+	} else if _, found := plugins.factories[name]; found {
+		panic("commander: RegisterPlugin called twice for plugin " + name)
+	}
+	plugins.factories[name] = factory
+}
+
+// registeredPlugin creates the plugin registered under the name given, or returns nil if there is
+// none.
+func registeredPlugin(name string) interface{} {
+	plugins.RLock()
+	defer plugins.RUnlock()
+	if factory, found := plugins.factories[name]; found {
+		return factory()
+	}
+	return nil
+}
+
+// registeredPluginNames returns the sorted names of all the registered plugins.
+func registeredPluginNames() []string {
+	plugins.RLock()
+	defer plugins.RUnlock()
+	names := []string{}
+	for name := range plugins.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// mountsPlugins returns true if one of the fields of the application has the plugins directive.
+func mountsPlugins(st reflect.Type) bool {
+	for i := 0; i < st.NumField(); i++ {
+		if alias, ok := st.Field(i).Tag.Lookup(FieldTag); ok && alias == PluginsDirective {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+package commander_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/apourchet/commander"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExternalPlugins(t *testing.T) {
+	dir, err := ioutil.TempDir("", "commander-plugins")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "output")
+	script := "#!/bin/sh\necho \"$@\" > " + output + "\necho \"$" + commander.PluginFlagsEnv + "\" >> " + output + "\n"
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "myapp-subapp-subsubapp-plugin"), []byte(script), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "myapp-failing"), []byte("#!/bin/sh\nexit 3\n"), 0755))
+	streams := "#!/bin/sh\necho \"$@\"\necho \"$" + commander.PluginFlagsEnv + "\"\ncat\necho oops >&2\n"
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "myapp-subapp-subsubapp-streams"), []byte(streams), 0755))
+
+	path := os.Getenv("PATH")
+	defer os.Setenv("PATH", path)
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+path)
+
+	cmd := commander.New()
+	cmd.UsageOutput = ioutil.Discard
+	cmd.ExternalPlugins = true
+
+	t.Run("runs_plugin", func(t *testing.T) {
+		app := &Application{SubApp: &SubApplication{SubSubApp: &SubSubApplication{}}}
+		args := []string{"--intflag", "3", "subapp", "--subintflag", "4", "subsubapp", "plugin", "a", "b"}
+		err := cmd.RunCLI(app, args)
+		require.NoError(t, err)
+
+		content, err := ioutil.ReadFile(output)
+		require.NoError(t, err)
+		lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+		require.Equal(t, "a b", lines[0])
+		require.Equal(t, `{"intflag":"3","subintflag":"4"}`, lines[1])
+	})
+
+	t.Run("streams", func(t *testing.T) {
+		var stdout, usage bytes.Buffer
+		cmd := commander.New()
+		cmd.ExternalPlugins = true
+		cmd.Stdin, cmd.Stdout, cmd.UsageOutput = strings.NewReader("input\n"), &stdout, &usage
+		require.NoError(t, cmd.RunCLI(&Application{SubApp: &SubApplication{SubSubApp: &SubSubApplication{}}}, []string{"subapp", "subsubapp", "streams", "x"}))
+		require.Equal(t, "x\n{}\ninput\n", stdout.String())
+		require.Equal(t, "oops\n", usage.String())
+	})
+
+	t.Run("plugin_fails", func(t *testing.T) {
+		err := cmd.RunCLI(&Application{}, []string{"failing"})
+		require.Error(t, err)
+	})
+
+	t.Run("no_plugin", func(t *testing.T) {
+		err := cmd.RunCLI(&Application{}, []string{"missing"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to find possible method")
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		cmd := commander.New()
+		cmd.UsageOutput = ioutil.Discard
+		err := cmd.RunCLI(&Application{}, []string{"failing"})
+		require.Contains(t, err.Error(), "failed to find possible method")
+	})
+}
+
+type PluginHost struct {
+	_ struct{} `commander:"plugins"`
+
+	SubApp *SubApplication `commander:"subcommand=subapp,Use subapp commands"`
+}
+
+type Plugin struct {
+	Name string `commander:"flag=name"`
+}
+
+var pluginGreetings = []string{}
+
+func (plugin *Plugin) Greet() {
+	pluginGreetings = append(pluginGreetings, plugin.Name)
+}
+
+func init() {
+	commander.RegisterPlugin("plugged", func() interface{} { return &Plugin{} })
+}
+
+func TestRegisteredPlugins(t *testing.T) {
+	t.Run("dispatch", func(t *testing.T) {
+		pluginGreetings = []string{}
+		defer func() { pluginGreetings = []string{} }()
+		err := commander.New().RunCLI(&PluginHost{}, []string{"plugged", "--name", "world", "greet"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"world"}, pluginGreetings)
+	})
+
+	t.Run("not_mounted", func(t *testing.T) {
+		cmd := commander.New()
+		cmd.UsageOutput = ioutil.Discard
+		err := cmd.RunCLI(&Application{}, []string{"plugged", "greet"})
+		require.Error(t, err)
+	})
+
+	t.Run("usage", func(t *testing.T) {
+		expected := `Usage of CLI:
+
+Sub-Commands:
+  plugged  |  No description for this subcommand
+  subapp  |  Use subapp commands
+`
+		assertEqualLines(t, expected, commander.New().Usage(&PluginHost{}))
+	})
+
+	t.Run("duplicate", func(t *testing.T) {
+		require.Panics(t, func() {
+			commander.RegisterPlugin("plugged", func() interface{} { return &Plugin{} })
+		})
+	})
+}
@@ -0,0 +1,43 @@
+package commander
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// PrintConfigFlagName is the name of the flag added by the Commander when PrintConfigFlag is set.
+const PrintConfigFlagName = "print-config"
+
+// writeConfig writes the resolved value of every flag of the flagsets given and where it comes
+// from, one flagset after the other, instead of running the command they were parsed for. The
+// values of the flags with the secret option are redacted.
+//
+//	myapp:
+//	  -region  eu        (flag)
+//	  -token   REDACTED  (env TOKEN)
+//	myapp deploy:
+//	  -retries  3  (default)
+func writeConfig(w io.Writer, flagsets []*FlagSet) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, flagset := range flagsets {
+		names := flagset.targetNames()
+		if len(names) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(tw, "%v:\n", flagset.Name())
+		for _, name := range names {
+			target := flagset.targets[name]
+			value, source := target.value(), target.source()
+			if target.secret {
+				value = redactedValue
+			}
+			if source == "env" {
+				source += " " + target.env
+			}
+			fmt.Fprintf(tw, "  -%v\t%v\t(%v)\n", name, value, source)
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,59 @@
+package commander_test
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/apourchet/commander"
+	"github.com/stretchr/testify/require"
+)
+
+type ConfiguredApplication struct {
+	Region string `commander:"flag,name=region,env=CONFIGURED_REGION"`
+	Token  string `commander:"flag,name=token,env=CONFIGURED_TOKEN,secret"`
+
+	Sub *ConfiguredSubApplication `commander:"subcommand=sub"`
+}
+
+type ConfiguredSubApplication struct {
+	Retries int `commander:"flag,name=retries,default=3"`
+	ran     bool
+}
+
+func (app *ConfiguredSubApplication) Deploy(target string) { app.ran = true }
+
+func TestPrintConfig(t *testing.T) {
+	os.Setenv("CONFIGURED_TOKEN", "s3cr3t")
+	defer os.Unsetenv("CONFIGURED_TOKEN")
+
+	var out bytes.Buffer
+	cmd := commander.New()
+	cmd.Stdout = &out
+	cmd.PrintConfigFlag = true
+
+	app := &ConfiguredApplication{Sub: &ConfiguredSubApplication{}}
+	require.NoError(t, cmd.RunCLI(app, []string{"--print-config", "--region=eu", "sub", "deploy", "prod"}))
+	require.False(t, app.Sub.ran)
+	expected := `CLI:
+  -region  eu        (flag)
+  -token   REDACTED  (env CONFIGURED_TOKEN)
+CLI sub:
+  -retries  3  (default)
+`
+	require.Equal(t, expected, out.String())
+
+	flagset, err := cmd.GetFlagSet(app, "CLI")
+	require.NoError(t, err)
+	require.NoError(t, flagset.Parse([]string{"--region=us"}))
+	sources := map[string]string{}
+	for _, info := range flagset.Targets() {
+		sources[info.Name] = info.Source
+	}
+	require.Equal(t, map[string]string{"region": "flag", "token": "env"}, sources)
+
+	out.Reset()
+	require.NoError(t, cmd.RunCLI(app, []string{"sub", "deploy", "prod"}))
+	require.True(t, app.Sub.ran)
+	require.Empty(t, out.String())
+}
@@ -0,0 +1,79 @@
+package commander
+
+import (
+	"io/ioutil"
+	"reflect"
+	"strings"
+)
+
+// Reconstruct returns the command line arguments that run the command path given on the application
+// with the current values of its flags, so that a command can run itself again elsewhere, like under
+// sudo or on a remote host, with identical settings. The path is made of the subcommands, the
+// command and its arguments. The flags of each level follow the subcommand that defines them, and
+// the flags of the flagstructs of the command follow the command. Flags with empty values are left
+// out, unless they have a default value that they were changed from. The arguments of the command
+// are preceded by "--" if one of them starts with a dash, so that they are not parsed as flags.
+func Reconstruct(app interface{}, path []string) ([]string, error) {
+	commander := New()
+	commander.UsageOutput = ioutil.Discard
+	commander.keepFlagValues = true
+	appname := getCLIName(app)
+	flagset, err := commander.GetFlagSet(app, appname)
+	if err != nil {
+		return nil, err
+	}
+	out := flagArguments(flagset)
+
+	ancestors, commandPath := []interface{}{}, []string{}
+	for i, word := range path {
+		subapp, _, err := subCommand(app, word)
+		if err != nil {
+			return nil, err
+		} else if subapp != nil {
+			if flagset, err = commander.GetFlagSet(subapp, appname+" "+word); err != nil {
+				return nil, err
+			}
+			out = append(append(out, word), flagArguments(flagset)...)
+			ancestors, commandPath = append(ancestors, app), append(commandPath, word)
+			app, appname = subapp, appname+" "+word
+			continue
+		}
+
+		if found, err := hasCommand(app, word); err != nil {
+			return nil, err
+		} else if !found {
+			return append(out, path[i:]...), nil
+		}
+		scoped := map[string]interface{}{word: app}
+		for j, ancestor := range ancestors {
+			scoped[strings.Join(append(commandPath[j:len(ancestors):len(ancestors)], word), " ")] = ancestor
+		}
+		if flagset, err = commander.commandFlagSet(scoped, appname, word); err != nil {
+			return nil, err
+		}
+		out = append(append(out, word), flagArguments(flagset)...)
+		for _, arg := range path[i+1:] {
+			if strings.HasPrefix(arg, "-") {
+				out = append(out, "--")
+				break
+			}
+		}
+		return append(out, path[i+1:]...), nil
+	}
+	return out, nil
+}
+
+// flagArguments returns the arguments that set the flags of the set to their current values,
+// sorted by flag name. Flags with empty values are left out, unless their default value is not
+// empty.
+func flagArguments(flagset *FlagSet) []string {
+	out := []string{}
+	for _, info := range flagset.Targets() {
+		if info.Type.Kind() == reflect.Bool {
+			out = append(out, "--"+info.Name+"="+info.Value)
+		} else if info.Value != "" || flagset.targets[info.Name].def != "" {
+			out = append(out, "--"+info.Name, info.Value)
+		}
+	}
+	return out
+}
@@ -0,0 +1,67 @@
+package commander_test
+
+import (
+	"testing"
+
+	"github.com/apourchet/commander"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconstruct(t *testing.T) {
+	app := &Application{IntFlag: 10, SubApp: &SubApplication{SubIntFlag: 3}}
+	args, err := commander.Reconstruct(app, []string{"subapp", "opfour", "test=testing"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"--intflag", "10", "subapp", "--subintflag", "3", "opfour", "test=testing"}, args)
+
+	rerun := &Application{SubApp: &SubApplication{}}
+	require.NoError(t, commander.New().RunCLI(rerun, args))
+	require.Equal(t, 10, rerun.IntFlag)
+	require.Equal(t, 3, rerun.SubApp.SubIntFlag)
+	require.Equal(t, 1, rerun.SubApp.count)
+}
+
+func TestReconstructFlagStructs(t *testing.T) {
+	app := &Application3{A: "a"}
+	app.B.B1, app.C.C2 = "common", "c2"
+	args, err := commander.Reconstruct(app, []string{"cmd1", "arg"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"--a", "a", "cmd1", "--common", "common", "arg"}, args)
+
+	scoping := &ScopingApplication{Manage: &CopyApplication{}}
+	scoping.Copy.Force = true
+	args, err = commander.Reconstruct(scoping, []string{"manage", "copy", "src", "dst"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"manage", "copy", "--force=true", "src", "dst"}, args)
+
+	rerun := &ScopingApplication{Manage: &CopyApplication{}}
+	require.NoError(t, commander.New().RunCLI(rerun, args))
+	require.True(t, rerun.Copy.Force)
+}
+
+type ReconstructApplication struct {
+	Region string `commander:"flag,name=region,default=eu"`
+
+	args []string
+}
+
+func (app *ReconstructApplication) Run(args []string) { app.args = args }
+
+func TestReconstructRoundTrip(t *testing.T) {
+	app := &ReconstructApplication{}
+	require.NoError(t, commander.New().RunCLI(app, []string{"--region", "", "run", "x"}))
+	require.Equal(t, "", app.Region)
+
+	args, err := commander.Reconstruct(app, []string{"run", "-x", "y"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"--region", "", "run", "--", "-x", "y"}, args)
+
+	rerun := &ReconstructApplication{}
+	require.NoError(t, commander.New().RunCLI(rerun, args))
+	require.Equal(t, "", rerun.Region)
+	require.Equal(t, []string{"-x", "y"}, rerun.args)
+
+	_, err = commander.Reconstruct(&struct {
+		Port int `commander:"flag,name=port,max=many"`
+	}{}, nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,146 @@
+package commander
+
+import (
+	"flag"
+	"io/ioutil"
+	"reflect"
+	"strings"
+
+	"github.com/apourchet/commander/utils"
+)
+
+// redactedValue replaces the values of the redacted flags in the files that the Commander writes.
+const redactedValue = "REDACTED"
+
+// redaction lists the flags whose values are kept out of the command lines that the Commander
+// writes to files, like the history and the log file. The boolean flags of the application are
+// listed as well, so that a redacted boolean flag given without a value does not take the next
+// argument along with it.
+type redaction struct {
+	flags map[string]bool
+	bools map[string]bool
+}
+
+func newRedaction() redaction {
+	return redaction{flags: map[string]bool{}, bools: map[string]bool{}}
+}
+
+// apply returns the arguments with the values of the redacted flags replaced by redactedValue, and
+// whether any of them was replaced.
+func (redacted redaction) apply(arguments []string) ([]string, bool) {
+	out := make([]string, 0, len(arguments))
+	replaced := false
+	for i := 0; i < len(arguments); i++ {
+		arg := arguments[i]
+		name := strings.TrimLeft(arg, "-")
+		if arg == "--" {
+			return append(out, arguments[i:]...), replaced
+		} else if name == arg || !redacted.flags[strings.SplitN(name, "=", 2)[0]] {
+			out = append(out, arg)
+		} else if strings.Contains(name, "=") {
+			out = append(out, strings.SplitN(arg, "=", 2)[0]+"="+redactedValue)
+			replaced = true
+		} else if !redacted.bools[name] && i+1 < len(arguments) {
+			out = append(out, arg, redactedValue)
+			replaced = true
+			i++
+		} else {
+			out = append(out, arg)
+		}
+	}
+	return out, replaced
+}
+
+// redactedFlags returns the flags whose values the Commander keeps out of the files it writes: the
+// flags with the secret option and the flags listed by its History.
+func (commander Commander) redactedFlags(app interface{}) redaction {
+	redacted := commander.secretFlags(app)
+	if commander.History != nil {
+		for _, name := range commander.History.Redacted {
+			redacted.flags[name] = true
+		}
+	}
+	return redacted
+}
+
+// secretFlags returns the flags with the secret option anywhere in the application: at every level
+// of its tree of subcommands and in the flagstructs of their commands. The boolean flags are
+// collected along the way.
+func (commander Commander) secretFlags(app interface{}) redaction {
+	commander.UsageOutput = ioutil.Discard
+	commander.keepFlagValues = true
+	finder := &secretFinder{commander: commander, visited: map[interface{}]bool{}, secrets: newRedaction()}
+	finder.search(app, getCLIName(app), nil, nil)
+	return finder.secrets
+}
+
+type secretFinder struct {
+	commander Commander
+	visited   map[interface{}]bool
+	secrets   redaction
+}
+
+// search adds the secret flags of the application, named appname and reached through the
+// ancestors and the command path given, and searches its subcommands in turn.
+func (finder *secretFinder) search(app interface{}, appname string, ancestors []interface{}, path []string) {
+	if reflect.ValueOf(app).Kind() == reflect.Ptr {
+		if finder.visited[app] {
+			return
+		}
+		finder.visited[app] = true
+	}
+
+	if flagset, err := finder.commander.GetFlagSet(app, appname); err == nil {
+		finder.add(flagset)
+	}
+	for _, cmd := range commandNames(app) {
+		scoped := map[string]interface{}{cmd: app}
+		for i, ancestor := range ancestors {
+			scoped[strings.Join(append(path[i:len(ancestors):len(ancestors)], cmd), " ")] = ancestor
+		}
+		if flagset, err := finder.commander.commandFlagSet(scoped, appname, cmd); err == nil {
+			finder.add(flagset)
+		}
+	}
+
+	st, valid := utils.DerefType(app)
+	if !valid {
+		return
+	}
+	ancestors = append(ancestors[:len(ancestors):len(ancestors)], app)
+	for i := 0; i < st.NumField(); i++ {
+		split := strings.SplitN(st.Field(i).Tag.Get(FieldTag), "=", 2)
+		if len(split) != 2 || split[0] != SubcommandDirective {
+			continue
+		}
+		directive, _ := parseSubcommandDirective(split[1])
+		if directive.renamed != "" {
+			continue
+		}
+		subapp, _, _ := subCommand(app, directive.cmd)
+		if v := reflect.ValueOf(subapp); subapp != nil && (v.Kind() != reflect.Ptr || !v.IsNil()) {
+			finder.search(subapp, appname+" "+directive.cmd, ancestors, append(path[:len(path):len(path)], directive.cmd))
+		}
+	}
+	for _, cmd := range dynamicSubcommandNames(app) {
+		finder.search(dynamicSubcommand(app, cmd), appname+" "+cmd, ancestors, append(path[:len(path):len(path)], cmd))
+	}
+}
+
+func (finder *secretFinder) add(flagset *FlagSet) {
+	for name, target := range flagset.targets {
+		if target.secret {
+			finder.secrets.flags[name] = true
+		}
+	}
+	for alias, target := range flagset.aliases {
+		if aliased := flagset.targets[target.name]; aliased != nil && aliased.secret {
+			finder.secrets.flags[alias] = true
+		}
+	}
+	flagset.VisitAll(func(f *flag.Flag) {
+		if isBoolFlag(f) {
+			finder.secrets.bools[f.Name] = true
+		}
+	})
+}
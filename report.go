@@ -0,0 +1,57 @@
+package commander
+
+import (
+	"context"
+	"time"
+)
+
+// Report describes an invocation of an application that was run through RunReport.
+type Report struct {
+	// Path is the path of commands typed on the command line to reach the command.
+	Path []string
+
+	// Command is the command of the application that was run.
+	Command string
+
+	// Flags are the flags that were set on the command line or by their environment variable.
+	Flags []FlagReport
+
+	// Arguments are the values that the command was called with, after their conversion to the
+	// types of its parameters.
+	Arguments []interface{}
+
+	// Duration is the time that the whole invocation took.
+	Duration time.Duration
+
+	// Err is the error returned by the command itself, if any.
+	Err error
+}
+
+// FlagReport is a flag that was set during an invocation.
+type FlagReport struct {
+	Name  string
+	Value string
+
+	// Level is the path of commands at which the flag is defined.
+	Level []string
+
+	// Source tells where the value of the flag comes from, like the Source of FlagInfo: "flag" if
+	// it was given on the command line and "env" if it was set by its environment variable.
+	Source string
+}
+
+// RunReport runs the application like RunCLI, and returns a report of what was run along with the
+// error that RunCLI would have returned. The report is returned even if the invocation failed.
+func (commander Commander) RunReport(app interface{}, arguments []string) (*Report, error) {
+	inv := &invocation{}
+	start := time.Now()
+	err := commander.run(context.Background(), app, arguments, inv)
+	return &Report{
+		Path:      inv.path,
+		Command:   inv.command,
+		Flags:     inv.flags,
+		Arguments: inv.arguments,
+		Duration:  time.Since(start),
+		Err:       inv.appErr,
+	}, err
+}
@@ -0,0 +1,56 @@
+package commander_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/apourchet/commander"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunReport(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		app := &Application{SubApp: &SubApplication{}}
+		report, err := commander.New().RunReport(app, []string{"--intflag", "3", "subapp", "--subintflag=4", "openv", "name", "a=1"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"subapp", "openv"}, report.Path)
+		require.Equal(t, "openv", report.Command)
+		require.Equal(t, []commander.FlagReport{
+			{Name: "intflag", Value: "3", Level: []string{}, Source: "flag"},
+			{Name: "subintflag", Value: "4", Level: []string{"subapp"}, Source: "flag"},
+		}, report.Flags)
+		require.Equal(t, []interface{}{"name", map[string]int{"a": 1}}, report.Arguments)
+		require.NoError(t, report.Err)
+		require.True(t, report.Duration > 0)
+	})
+
+	t.Run("env_flags", func(t *testing.T) {
+		os.Setenv("CONFIGURED_REGION", "eu")
+		defer os.Unsetenv("CONFIGURED_REGION")
+
+		app := &ConfiguredApplication{Sub: &ConfiguredSubApplication{}}
+		report, err := commander.New().RunReport(app, []string{"--token", "abc", "sub", "deploy", "prod"})
+		require.NoError(t, err)
+		require.Equal(t, []commander.FlagReport{
+			{Name: "region", Value: "eu", Level: []string{}, Source: "env"},
+			{Name: "token", Value: "abc", Level: []string{}, Source: "flag"},
+		}, report.Flags)
+	})
+
+	t.Run("application_error", func(t *testing.T) {
+		report, err := commander.New().RunReport(&Application{}, []string{"opthree"})
+		require.Equal(t, errTest, err)
+		require.Equal(t, errTest, report.Err)
+		require.Equal(t, []string{"opthree"}, report.Path)
+	})
+
+	t.Run("dispatch_error", func(t *testing.T) {
+		cmd := commander.New()
+		cmd.UsageOutput = ioutil.Discard
+		report, err := cmd.RunReport(&Application{}, []string{"optwo", "notanint"})
+		require.Error(t, err)
+		require.NoError(t, report.Err)
+		require.Equal(t, "optwo", report.Command)
+	})
+}
@@ -0,0 +1,83 @@
+package commander
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// shellSafeChars are the characters that never need to be quoted in a shell word.
+const shellSafeChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_@%+=:,./-"
+
+// ShellJoin joins the arguments into a single string that a POSIX shell splits back into the same
+// arguments. Arguments that contain spaces, quotes, newlines or any other special character are
+// single-quoted.
+func ShellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// shellQuote quotes the argument for a POSIX shell if it needs to be.
+func shellQuote(arg string) string {
+	if arg == "" {
+		return "''"
+	} else if strings.Trim(arg, shellSafeChars) == "" {
+		return arg
+	}
+	return "'" + strings.Replace(arg, "'", `'\''`, -1) + "'"
+}
+
+// ShellSplit splits the line into arguments like a POSIX shell does, without expanding anything:
+// words are separated by blanks, single quotes keep everything up to the next single quote as is,
+// double quotes keep everything but the \", \\, \$ and \` escapes, and a backslash outside of
+// quotes keeps the next character as is. It is the inverse of ShellJoin.
+func ShellSplit(line string) ([]string, error) {
+	args := []string{}
+	var word strings.Builder
+	inWord := false
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; c {
+		case ' ', '\t', '\n', '\r':
+			if inWord {
+				args, inWord = append(args, word.String()), false
+				word.Reset()
+			}
+			continue
+		case '\\':
+			if i+1 == len(line) {
+				return nil, errors.New("unterminated backslash escape")
+			}
+			i++
+			word.WriteByte(line[i])
+		case '\'':
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("unterminated single quote")
+			}
+			word.WriteString(line[i+1 : i+1+end])
+			i += 1 + end
+		case '"':
+			end := i + 1
+			for ; end < len(line) && line[end] != '"'; end++ {
+				if line[end] == '\\' && end+1 < len(line) && strings.IndexByte("\"\\$`", line[end+1]) >= 0 {
+					end++
+				}
+				word.WriteByte(line[end])
+			}
+			if end == len(line) {
+				return nil, errors.New("unterminated double quote")
+			}
+			i = end
+		default:
+			word.WriteByte(c)
+		}
+		inWord = true
+	}
+	if inWord {
+		args = append(args, word.String())
+	}
+	return args, nil
+}
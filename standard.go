@@ -0,0 +1,66 @@
+package commander
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// NoColorEnv is the environment variable that disables colored output when it is set to anything,
+// following https://no-color.org.
+const NoColorEnv = "NO_COLOR"
+
+// JSONOutputFormat is the value of the --output flag of the StandardFlags that makes the Commander
+// write the values streamed by the commands as JSON.
+const JSONOutputFormat = "json"
+
+// StandardFlags is the bundle of flags that most CLIs share. Applications embed it as a
+// flagstruct:
+//
+//	type App struct {
+//		Std commander.StandardFlags `commander:"flagstruct"`
+//	}
+//
+// The Commander recognizes it once the flags are parsed: --quiet discards the usage output of the
+// Commander, --verbose traces the execution of the command to it, --no-color makes Color return
+// false and the Commander leave out colors for the rest of the invocation, --output=json writes the values streamed by the command as JSON and --no-input leaves the
+// commands with an empty Stdin so that they never wait for input.
+type StandardFlags struct {
+	Quiet   bool   `commander:"flag=quiet,Only print errors and the output of the command"`
+	Verbose bool   `commander:"flag=verbose,Print more details about what the command does"`
+	NoColor bool   `commander:"flag=no-color,Disable colored output"`
+	Output  string `commander:"flag=output,Format of the output of the command"`
+	NoInput bool   `commander:"flag=no-input,Never prompt for input"`
+}
+
+// Color returns true unless colors were disabled with --no-color or the NO_COLOR environment
+// variable.
+func (flags *StandardFlags) Color() bool {
+	return !flags.NoColor && os.Getenv(NoColorEnv) == ""
+}
+
+// withStandardFlags returns the Commander configured by the first StandardFlags found among the
+// structs given.
+func (commander Commander) withStandardFlags(structs []interface{}) Commander {
+	for _, obj := range structs {
+		flags, ok := obj.(*StandardFlags)
+		if !ok {
+			continue
+		}
+		if flags.Quiet {
+			commander.UsageOutput = ioutil.Discard
+		}
+		if flags.Verbose && commander.Trace == nil {
+			commander.Trace = commander.UsageOutput
+		}
+		if flags.NoColor {
+			commander.noColor = true
+		}
+		if flags.NoInput {
+			commander.Stdin = strings.NewReader("")
+		}
+		commander.outputFormat = flags.Output
+		break
+	}
+	return commander
+}
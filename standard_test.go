@@ -0,0 +1,66 @@
+package commander_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/apourchet/commander"
+	"github.com/stretchr/testify/require"
+)
+
+type StandardApplication struct {
+	Std commander.StandardFlags `commander:"flagstruct"`
+
+	read string
+}
+
+func (app *StandardApplication) Read(value string) { app.read = value }
+
+func (app *StandardApplication) List() <-chan string {
+	items := make(chan string, 2)
+	items <- "a"
+	items <- "b"
+	close(items)
+	return items
+}
+
+func TestStandardFlags(t *testing.T) {
+	var buf bytes.Buffer
+	cmd := commander.New()
+	cmd.UsageOutput = &buf
+	cmd.StdinArgument = true
+	cmd.Stdin = strings.NewReader("piped")
+
+	app := &StandardApplication{}
+	require.NoError(t, cmd.RunCLI(app, []string{"--no-input", "--verbose", "--output", "json", "read", "-"}))
+	require.Equal(t, "", app.read)
+	require.True(t, app.Std.Verbose)
+	require.Equal(t, "json", app.Std.Output)
+	require.Contains(t, buf.String(), "commander: CLI: executing command read")
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	require.NoError(t, cmd.RunCLI(&StandardApplication{}, []string{"list"}))
+	require.NoError(t, cmd.RunCLI(&StandardApplication{}, []string{"--output", "json", "list"}))
+	require.Equal(t, "a\nb\n\"a\"\n\"b\"\n", out.String())
+
+	buf.Reset()
+	require.Error(t, cmd.RunCLI(&StandardApplication{}, []string{"--quiet", "read"}))
+	require.Empty(t, buf.String())
+	require.Error(t, cmd.RunCLI(&StandardApplication{}, []string{"read"}))
+	require.NotEmpty(t, buf.String())
+
+	cmd.ColorErrors = true
+	require.Equal(t, "\x1b[1m\x1b[31merror:\x1b[0m boom\n", cmd.FormatError(fmt.Errorf("boom")))
+	require.NoError(t, cmd.RunCLI(app, []string{"--no-color", "read", "x"}))
+	require.False(t, app.Std.Color())
+	require.Empty(t, os.Getenv(commander.NoColorEnv))
+	require.Equal(t, "\x1b[1m\x1b[31merror:\x1b[0m boom\n", cmd.FormatError(fmt.Errorf("boom")))
+
+	os.Setenv(commander.NoColorEnv, "1")
+	defer os.Unsetenv(commander.NoColorEnv)
+	require.Equal(t, "error: boom\n", cmd.FormatError(fmt.Errorf("boom")))
+}
@@ -0,0 +1,90 @@
+package commander
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/apourchet/commander/utils"
+	"github.com/pkg/errors"
+)
+
+// isStream returns true if the values of the type are streams of output that a command can return:
+// channels that can be received from, or iterators of the form func(yield func(T) bool).
+func isStream(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Chan:
+		return t.ChanDir()&reflect.RecvDir != 0
+	case reflect.Func:
+		return t.NumIn() == 1 && t.NumOut() == 0 && t.In(0).Kind() == reflect.Func &&
+			t.In(0).NumIn() == 1 && t.In(0).NumOut() == 1 && t.In(0).Out(0).Kind() == reflect.Bool
+	}
+	return false
+}
+
+// drainStream writes each value of the stream to the writer in the format given as soon as the
+// command produces it, until the channel is closed or the iterator returns. Draining a channel stops
+// early if the context is done.
+func drainStream(ctx context.Context, w io.Writer, stream reflect.Value, format string) error {
+	if stream.IsNil() {
+		return nil
+	}
+	if stream.Kind() == reflect.Chan {
+		cases := []reflect.SelectCase{
+			{Dir: reflect.SelectRecv, Chan: stream},
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+		}
+		for {
+			chosen, item, ok := reflect.Select(cases)
+			if chosen == 1 {
+				return ctx.Err()
+			} else if !ok {
+				return nil
+			} else if err := writeStreamItem(w, item, format); err != nil {
+				return err
+			}
+		}
+	}
+
+	var err error
+	yield := reflect.MakeFunc(stream.Type().In(0), func(args []reflect.Value) []reflect.Value {
+		if err == nil {
+			err = ctx.Err()
+		}
+		if err == nil {
+			err = writeStreamItem(w, args[0], format)
+		}
+		return []reflect.Value{reflect.ValueOf(err == nil)}
+	})
+	stream.Call([]reflect.Value{yield})
+	return err
+}
+
+// writeStreamItem writes a value of a stream on its own line: as JSON if that is the format given,
+// otherwise with its String method if it has one, like a flag value if it is of a type that flags
+// can have, and as JSON if it is neither.
+func writeStreamItem(w io.Writer, item reflect.Value, format string) error {
+	for item.Kind() == reflect.Interface && !item.IsNil() {
+		item = item.Elem()
+	}
+	text := ""
+	if format == JSONOutputFormat {
+		content, err := json.Marshal(item.Interface())
+		if err != nil {
+			return errors.Wrap(err, "failed to render command output")
+		}
+		text = string(content)
+	} else if stringer, ok := item.Interface().(fmt.Stringer); ok {
+		text = stringer.String()
+	} else if str, err := utils.StringifyValue(item); err == nil {
+		text = str
+	} else if content, err := json.Marshal(item.Interface()); err == nil {
+		text = string(content)
+	} else {
+		return errors.Wrap(err, "failed to render command output")
+	}
+	_, err := fmt.Fprintln(w, text)
+	return err
+}
@@ -0,0 +1,84 @@
+package commander_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/apourchet/commander"
+	"github.com/stretchr/testify/require"
+)
+
+type StreamingApplication struct{}
+
+type tick struct {
+	N    int    `json:"n"`
+	Name string `json:"name"`
+}
+
+func (app *StreamingApplication) Count(n int) <-chan int {
+	ch := make(chan int)
+	go func() {
+		defer close(ch)
+		for i := 1; i <= n; i++ {
+			ch <- i
+		}
+	}()
+	return ch
+}
+
+func (app *StreamingApplication) Ticks(n int) (chan tick, error) {
+	if n < 0 {
+		return nil, errors.New("negative count")
+	}
+	ch := make(chan tick, n)
+	for i := 0; i < n; i++ {
+		ch <- tick{N: i, Name: fmt.Sprintf("tick%d", i)}
+	}
+	close(ch)
+	return ch, nil
+}
+
+func (app *StreamingApplication) Words(words []string) func(yield func(string) bool) {
+	return func(yield func(string) bool) {
+		for _, word := range words {
+			if !yield(word) {
+				return
+			}
+		}
+	}
+}
+
+func (app *StreamingApplication) Forever(ctx context.Context) <-chan time.Duration {
+	return make(chan time.Duration)
+}
+
+func TestStreamingOutput(t *testing.T) {
+	var out bytes.Buffer
+	cmd := commander.New()
+	cmd.Stdout = &out
+
+	require.NoError(t, cmd.RunCLI(&StreamingApplication{}, []string{"count", "3"}))
+	require.Equal(t, "1\n2\n3\n", out.String())
+
+	out.Reset()
+	require.NoError(t, cmd.RunCLI(&StreamingApplication{}, []string{"ticks", "2"}))
+	require.Equal(t, "{\"n\":0,\"name\":\"tick0\"}\n{\"n\":1,\"name\":\"tick1\"}\n", out.String())
+
+	out.Reset()
+	require.NoError(t, cmd.RunCLI(&StreamingApplication{}, []string{"words", "a", "b"}))
+	require.Equal(t, "a\nb\n", out.String())
+
+	err := cmd.RunCLI(&StreamingApplication{}, []string{"ticks", "--", "-1"})
+	require.EqualError(t, err, "negative count")
+}
+
+func TestStreamingOutputCancelled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := commander.New().RunCLIContext(ctx, &StreamingApplication{}, []string{"forever"})
+	require.Equal(t, context.DeadlineExceeded, err)
+}
@@ -0,0 +1,253 @@
+package commander
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/apourchet/commander/utils"
+)
+
+// undefinedFlagPrefix starts the error that the flag package returns for flags that are not defined.
+const undefinedFlagPrefix = "flag provided but not defined: "
+
+// flagCandidate is a defined flag that could have been meant instead of an undefined one. The hint
+// tells where the flag is defined, if it is not defined for the current command.
+type flagCandidate struct {
+	name string
+	hint string
+
+	// command is the command whose flagstruct defines the flag, if any, and isBool is true if the
+	// flag takes no value.
+	command string
+	isBool  bool
+}
+
+// parseFlags parses the arguments into the flagset. If one of the flags is not defined, the closest
+// defined flags are suggested in the usage output and in the error returned. The error is then
+// handled according to the error handling of the flagset, once it has been fully reported.
+func (commander Commander) parseFlags(app interface{}, flagset *FlagSet, arguments []string) error {
+	if commander.SlashFlags {
+		arguments = translateSlashFlags(flagset, arguments)
+	}
+	if commander.AbbreviatedFlags {
+		expanded, err := expandFlagPrefixes(flagset, arguments)
+		if err != nil {
+			return flagset.handleError(err)
+		}
+		arguments = expanded
+	}
+	if commander.WarnIrrelevantFlags && flagset.command != "" {
+		arguments = commander.dropIrrelevantFlags(app, flagset, arguments)
+	}
+	return flagset.handleError(commander.reportFlagError(app, flagset, arguments, flagset.parse(arguments)))
+}
+
+// reportFlagError adds the suggestions for an undefined flag to the error from parsing the flags.
+func (commander Commander) reportFlagError(app interface{}, flagset *FlagSet, arguments []string, err error) error {
+	if err == nil || !strings.HasPrefix(err.Error(), undefinedFlagPrefix) {
+		return err
+	}
+
+	name := strings.TrimLeft(strings.TrimPrefix(err.Error(), undefinedFlagPrefix), "-")
+	usage := UsageError{
+		Command:   flagset.Name(),
+		Arguments: arguments,
+		Token:     flagArgument(arguments, name),
+		Cause:     err.Error(),
+	}
+	if suggestions := suggestFlags(name, commander.flagCandidates(app, flagset)); len(suggestions) > 0 {
+		usage.Hint = fmt.Sprintf("did you mean %v?", strings.Join(suggestions, ", "))
+		fmt.Fprintln(commander.UsageOutput, usage.Hint)
+	}
+	return usage
+}
+
+// dropIrrelevantFlags removes the flags of the other commands of the application from the flags
+// given to the command of the flagset, warning about each of them on the usage output.
+func (commander Commander) dropIrrelevantFlags(app interface{}, flagset *FlagSet, arguments []string) []string {
+	irrelevant := map[string]flagCandidate{}
+	for _, candidate := range commander.flagCandidates(app, flagset) {
+		if candidate.command != "" && normalizeCommand(candidate.command) != normalizeCommand(flagset.command) {
+			irrelevant[candidate.name] = candidate
+		}
+	}
+
+	kept := []string{}
+	for i := 0; i < len(arguments); i++ {
+		arg := arguments[i]
+		if arg == "--" || arg == "-" || !strings.HasPrefix(arg, "-") {
+			return append(kept, arguments[i:]...)
+		}
+		split := strings.SplitN(strings.TrimLeft(arg, "-"), "=", 2)
+		name, takesValue := split[0], len(split) == 1 && i+1 < len(arguments)
+		if f := flagset.Lookup(name); f != nil {
+			kept = append(kept, arg)
+			if value, ok := f.Value.(interface{ IsBoolFlag() bool }); takesValue && !(ok && value.IsBoolFlag()) {
+				kept, i = append(kept, arguments[i+1]), i+1
+			}
+		} else if candidate, found := irrelevant[name]; found {
+			fmt.Fprintf(commander.UsageOutput, "warning: flag -%v is a flag of command %v, ignoring it for command %v\n",
+				name, candidate.command, flagset.command)
+			if takesValue && !candidate.isBool {
+				i++
+			}
+		} else {
+			return append(kept, arguments[i:]...)
+		}
+	}
+	return kept
+}
+
+// flagArgument returns the argument that sets the flag named, or nothing if there is none.
+func flagArgument(arguments []string, name string) string {
+	for _, arg := range arguments {
+		if arg == "--" {
+			break
+		} else if strings.SplitN(strings.TrimLeft(arg, "-"), "=", 2)[0] == name && strings.HasPrefix(arg, "-") {
+			return arg
+		}
+	}
+	return ""
+}
+
+// flagCandidates returns the flags of the flagset, along with the flags that are defined deeper in
+// the application: in the flagstructs of its commands and in its subcommands, whose flagsets are
+// set up with the options of the Commander.
+func (commander Commander) flagCandidates(app interface{}, flagset *FlagSet) []flagCandidate {
+	candidates := []flagCandidate{}
+	for name := range flagset.targets {
+		candidates = append(candidates, flagCandidate{name: name})
+	}
+
+	st, valid := utils.DerefType(app)
+	if !valid {
+		return candidates
+	}
+	quiet := commander
+	quiet.UsageOutput = ioutil.Discard
+	for i := 0; i < st.NumField(); i++ {
+		alias, ok := st.Field(i).Tag.Lookup(FieldTag)
+		split := strings.SplitN(alias, "=", 2)
+		if !ok || len(split) != 2 {
+			continue
+		}
+
+		directive, _ := parseSubcommandDirective(split[1])
+		var deeper *FlagSet
+		var hint, command string
+		if split[0] == FlagStructDirective {
+			deeper, _ = quiet.GetFlagSetWithCommand(app, "", directive.cmd)
+			hint, command = "flag of command "+directive.cmd, directive.cmd
+		} else if split[0] == SubcommandDirective {
+			if subapp, _, _ := subCommand(app, directive.cmd); subapp != nil {
+				deeper, _ = quiet.GetFlagSet(subapp, "")
+			}
+			hint = "flag of subcommand " + directive.cmd
+		}
+		if deeper == nil {
+			continue
+		}
+		for name, target := range deeper.targets {
+			if _, found := flagset.targets[name]; !found {
+				candidates = append(candidates, flagCandidate{name: name, hint: hint, command: command, isBool: target.IsBoolFlag()})
+			}
+		}
+	}
+	return candidates
+}
+
+// suggestFlags returns the candidates that are nearby to the name given, closest first.
+func suggestFlags(name string, candidates []flagCandidate) []string {
+	type scored struct {
+		flagCandidate
+		distance int
+	}
+	nearby := []scored{}
+	for _, candidate := range candidates {
+		distance := levenshtein(name, candidate.name)
+		if distance <= 2 && distance < len(name) {
+			nearby = append(nearby, scored{candidate, distance})
+		}
+	}
+	sort.Slice(nearby, func(i, j int) bool {
+		if nearby[i].distance != nearby[j].distance {
+			return nearby[i].distance < nearby[j].distance
+		}
+		return nearby[i].name < nearby[j].name
+	})
+
+	suggestions := []string{}
+	for _, candidate := range nearby {
+		suggestion := "-" + candidate.name
+		if candidate.hint != "" {
+			suggestion += " (" + candidate.hint + ")"
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	return suggestions
+}
+
+// commandCandidates returns the names of the commands and of the subcommands of the application.
+func commandCandidates(app interface{}) []string {
+	candidates := commandNames(app)
+	st, valid := utils.DerefType(app)
+	if !valid {
+		return candidates
+	}
+	for i := 0; i < st.NumField(); i++ {
+		split := strings.SplitN(st.Field(i).Tag.Get(FieldTag), "=", 2)
+		if len(split) == 2 && split[0] == SubcommandDirective {
+			directive, _ := parseSubcommandDirective(split[1])
+			candidates = append(candidates, directive.cmd)
+		}
+	}
+	candidates = append(candidates, dynamicSubcommandNames(app)...)
+	return append(candidates, mountedNames(app)...)
+}
+
+// suggestCommands returns the candidates that are close to the command given, closest first.
+func suggestCommands(cmd string, candidates []string) []string {
+	distances, suggestions := map[string]int{}, []string{}
+	for _, candidate := range candidates {
+		if _, found := distances[candidate]; found {
+			continue
+		} else if distance := levenshtein(cmd, candidate); distance <= 2 && distance < len(cmd) {
+			distances[candidate] = distance
+			suggestions = append(suggestions, candidate)
+		}
+	}
+	sort.Strings(suggestions)
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return distances[suggestions[i]] < distances[suggestions[j]]
+	})
+	return suggestions
+}
+
+// levenshtein returns the edit distance between the two strings.
+func levenshtein(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = previous[j-1] + cost
+			if previous[j]+1 < current[j] {
+				current[j] = previous[j] + 1
+			}
+			if current[j-1]+1 < current[j] {
+				current[j] = current[j-1] + 1
+			}
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
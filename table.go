@@ -0,0 +1,123 @@
+package commander
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/apourchet/commander/utils"
+	"github.com/pkg/errors"
+)
+
+// TruncationSuffix ends the cells that WriteTable shortened to fit in the maximum width.
+const TruncationSuffix = "..."
+
+// WriteTable writes the rows given, a slice of structs or of pointers to structs, as a table with
+// aligned columns. There is one column per exported field, with the field name in upper case as its
+// header unless the field is tagged with a ColumnDirective. Cells longer than maxWidth characters
+// are truncated; a maxWidth of 0 or less disables truncation.
+func WriteTable(w io.Writer, rows interface{}, maxWidth int) error {
+	v := reflect.ValueOf(rows)
+	if v.Kind() != reflect.Slice {
+		return fmt.Errorf("table rows need to be a slice, got %T", rows)
+	}
+
+	st := v.Type().Elem()
+	if st.Kind() == reflect.Ptr {
+		st = st.Elem()
+	}
+	if st.Kind() != reflect.Struct {
+		return fmt.Errorf("table rows need to be structs, got %v", v.Type().Elem())
+	}
+
+	fields, headers := []int{}, []string{}
+	for i := 0; i < st.NumField(); i++ {
+		field := st.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		header := strings.ToUpper(field.Name)
+		if alias, ok := field.Tag.Lookup(FieldTag); ok {
+			split := strings.SplitN(alias, "=", 2)
+			if len(split) == 2 && split[0] == ColumnDirective && split[1] == "-" {
+				continue
+			} else if len(split) == 2 && split[0] == ColumnDirective {
+				header = split[1]
+			}
+		}
+		fields, headers = append(fields, i), append(headers, header)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	writeTableRow(tw, headers, maxWidth)
+	for i := 0; i < v.Len(); i++ {
+		row, valid := utils.DerefValue(v.Index(i).Interface())
+		if !valid {
+			continue
+		}
+		cells := []string{}
+		for _, field := range fields {
+			cell, err := tableCell(row.Field(field))
+			if err != nil {
+				return fmt.Errorf("failed to write field %v of row %d: %v", st.Field(field).Name, i, err)
+			}
+			cells = append(cells, cell)
+		}
+		writeTableRow(tw, cells, maxWidth)
+	}
+	return tw.Flush()
+}
+
+// isTable returns true if the values of the type are written as tables when commands return them,
+// which is the case of the slices of structs and of pointers to structs.
+func isTable(t reflect.Type) bool {
+	if t.Kind() != reflect.Slice {
+		return false
+	}
+	elem := t.Elem()
+	if elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	return elem.Kind() == reflect.Struct
+}
+
+// writeRows writes the rows returned by a command to the Stdout of the Commander, as a table unless
+// the output format is JSON.
+func (commander Commander) writeRows(rows reflect.Value) error {
+	if commander.outputFormat != JSONOutputFormat {
+		return WriteTable(commander.Stdout, rows.Interface(), commander.TableCellWidth)
+	}
+	content, err := json.Marshal(rows.Interface())
+	if err != nil {
+		return errors.Wrap(err, "failed to render command output")
+	}
+	_, err = fmt.Fprintln(commander.Stdout, string(content))
+	return err
+}
+
+func writeTableRow(w io.Writer, cells []string, maxWidth int) {
+	for i, cell := range cells {
+		cell = strings.Replace(cell, "\t", " ", -1)
+		if runes := []rune(cell); maxWidth > 0 && len(runes) > maxWidth {
+			cut := maxWidth - len(TruncationSuffix)
+			if cut < 0 {
+				cut = 0
+			}
+			cell = string(runes[:cut]) + TruncationSuffix
+		}
+		cells[i] = cell
+	}
+	fmt.Fprintln(w, strings.Join(cells, "\t"))
+}
+
+func tableCell(v reflect.Value) (string, error) {
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return "", nil
+	} else if stringer, ok := v.Interface().(fmt.Stringer); ok {
+		return stringer.String(), nil
+	}
+	return utils.StringifyValue(v)
+}
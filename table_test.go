@@ -0,0 +1,74 @@
+package commander_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/apourchet/commander"
+	"github.com/stretchr/testify/require"
+)
+
+type TableRow struct {
+	Name        string
+	Age         time.Duration `commander:"column=AGE (H)"`
+	Description string
+	Internal    int `commander:"column=-"`
+	hidden      int
+}
+
+func TestWriteTable(t *testing.T) {
+	rows := []*TableRow{
+		{Name: "first", Age: time.Hour, Description: "A short one"},
+		{Name: "second", Age: 90 * time.Minute, Description: "A description that is much too long"},
+		nil,
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, commander.WriteTable(&buf, rows, 20))
+	require.Equal(t, ""+
+		"NAME    AGE (H)  DESCRIPTION\n"+
+		"first   1h0m0s   A short one\n"+
+		"second  1h30m0s  A description tha...\n", buf.String())
+
+	require.Error(t, commander.WriteTable(&buf, TableRow{}, 0))
+	require.Error(t, commander.WriteTable(&buf, []string{"a"}, 0))
+}
+
+type Row struct {
+	Name  string `json:"name"`
+	Count int    `commander:"column=#" json:"count"`
+}
+
+type TableApplication struct {
+	Std commander.StandardFlags `commander:"flagstruct"`
+}
+
+func (app *TableApplication) List() []Row {
+	return []Row{{Name: "apples", Count: 3}, {Name: "a much longer name", Count: 12}}
+}
+
+func (app *TableApplication) Fail() ([]Row, error) {
+	return []Row{{Name: "partial"}}, errTest
+}
+
+func TestCommandTables(t *testing.T) {
+	var out bytes.Buffer
+	cmd := commander.New()
+	cmd.Stdout = &out
+	cmd.TableCellWidth = 12
+
+	require.NoError(t, cmd.RunCLI(&TableApplication{}, []string{"list"}))
+	require.Equal(t, ""+
+		"NAME          #\n"+
+		"apples        3\n"+
+		"a much lo...  12\n", out.String())
+
+	out.Reset()
+	require.NoError(t, cmd.RunCLI(&TableApplication{}, []string{"--output", "json", "list"}))
+	require.Equal(t, `[{"name":"apples","count":3},{"name":"a much longer name","count":12}]`+"\n", out.String())
+
+	out.Reset()
+	require.Equal(t, errTest, cmd.RunCLI(&TableApplication{}, []string{"fail"}))
+	require.Empty(t, out.String())
+}
@@ -0,0 +1,41 @@
+package commander
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DebugEnv is the environment variable that makes New trace the dispatch of the applications to
+// stderr when it is set to a true value, like COMMANDER_DEBUG=1.
+const DebugEnv = "COMMANDER_DEBUG"
+
+// debugEnabled returns true if the DebugEnv environment variable is set to a true value.
+func debugEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv(DebugEnv))
+	return enabled
+}
+
+// tracef writes a line to the Trace output of the Commander, if it has one.
+func (commander Commander) tracef(format string, args ...interface{}) {
+	if commander.Trace == nil {
+		return
+	}
+	fmt.Fprintf(commander.Trace, "commander: "+format+"\n", args...)
+}
+
+// traceFlags traces the flags that the flagset defines and the ones that were set while parsing.
+func (commander Commander) traceFlags(level string, flagset *FlagSet) {
+	if commander.Trace == nil {
+		return
+	}
+	defined := []string{}
+	for name := range flagset.targets {
+		defined = append(defined, "-"+name)
+	}
+	sort.Strings(defined)
+	commander.tracef("%v: flags defined: [%v]", level, strings.Join(defined, " "))
+	commander.tracef("%v: flags parsed: %v, remaining arguments: %q", level, flagset.StringifyChanged(), flagset.Args())
+}
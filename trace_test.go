@@ -0,0 +1,35 @@
+package commander_test
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/apourchet/commander"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrace(t *testing.T) {
+	var buf bytes.Buffer
+	cmd := commander.New()
+	cmd.Trace = &buf
+	app := &Application{SubApp: &SubApplication{SubSubApp: &SubSubApplication{}}}
+	require.NoError(t, cmd.RunCLI(app, []string{"--intflag", "3", "subapp", "subsubapp", "opdeep"}))
+	require.Equal(t, `commander: myapp: flags defined: [-intflag]
+commander: myapp: flags parsed: [--intflag 3], remaining arguments: ["subapp" "subsubapp" "opdeep"]
+commander: myapp: subcommand subapp matched, descending into SubApplication
+commander: myapp subapp: flags defined: [-subintflag]
+commander: myapp subapp: flags parsed: [], remaining arguments: ["subsubapp" "opdeep"]
+commander: myapp subapp: subcommand subsubapp matched, descending into SubSubApplication
+commander: myapp subapp subsubapp: flags defined: []
+commander: myapp subapp subsubapp: flags parsed: [], remaining arguments: ["opdeep"]
+commander: myapp subapp subsubapp: command candidates [opdeep subsubapp CommanderDefault], chose the first that SubSubApplication implements: "opdeep"
+commander: myapp subapp subsubapp opdeep: flags defined: []
+commander: myapp subapp subsubapp opdeep: flags parsed: [], remaining arguments: []
+commander: myapp subapp subsubapp: executing command opdeep with arguments []
+`, buf.String())
+
+	os.Setenv(commander.DebugEnv, "1")
+	defer os.Unsetenv(commander.DebugEnv)
+	require.Equal(t, os.Stderr, commander.New().Trace)
+}
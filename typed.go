@@ -0,0 +1,111 @@
+package commander
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/apourchet/commander/utils"
+	"github.com/pkg/errors"
+)
+
+// TypedCommand is a subcommand built around a function that takes all of its inputs as a single
+// struct. The fields of that struct are populated from flags through the flag directive, and from
+// positional arguments through the arg directive.
+type TypedCommand[Args any] struct {
+	Args Args `commander:"flagstruct"`
+
+	fn func(Args) error
+}
+
+// Command returns a TypedCommand that calls fn when it runs. The result can be used as a
+// subcommand, by assigning it to a field with the subcommand directive or by returning it from the
+// factory of a plugin.
+func Command[Args any](fn func(Args) error) *TypedCommand[Args] {
+	return &TypedCommand[Args]{fn: fn}
+}
+
+// CommanderDefault populates the positional arguments of the arguments struct and calls the
+// function of the command with it.
+func (cmd *TypedCommand[Args]) CommanderDefault(args []string) error {
+	if err := bindArguments(&cmd.Args, args); err != nil {
+		return err
+	}
+	return cmd.fn(cmd.Args)
+}
+
+// bindArguments sets the fields of the object that have the arg directive to the positional
+// arguments given.
+func bindArguments(obj interface{}, args []string) error {
+	st, valid := utils.DerefType(obj)
+	if !valid {
+		return fmt.Errorf("arguments need to be a struct or a pointer to a struct")
+	}
+	v, _ := utils.DerefValue(obj)
+
+	for i := 0; i < st.NumField(); i++ {
+		field := st.Field(i)
+		alias, ok := field.Tag.Lookup(FieldTag)
+		if !ok {
+			continue
+		}
+		split := strings.SplitN(alias, "=", 2)
+		if split[0] != ArgDirective {
+			continue
+		} else if len(split) != 2 {
+			return fmt.Errorf("malformed tag on arguments: %v", alias)
+		}
+		name, _ := parseArgDirective(split[1])
+
+		if field.Type.Kind() == reflect.Slice {
+			extras, err := parseTrailingArguments(field.Type, args)
+			if err != nil {
+				return errors.Wrapf(err, "failed to parse argument %v", name)
+			}
+			v.Field(i).Set(extras)
+			args = nil
+			continue
+		} else if len(args) == 0 {
+			return fmt.Errorf("missing argument %v", name)
+		}
+
+		if err := utils.SetField(obj, field.Name, args[0]); err != nil {
+			return errors.Wrapf(err, "failed to parse argument %v", name)
+		}
+		args = args[1:]
+	}
+
+	if len(args) > 0 {
+		return fmt.Errorf("too many arguments: %v", strings.Join(args, " "))
+	}
+	return nil
+}
+
+// commandArguments returns the positional arguments of the command, from the fields of its
+// arguments struct that have the arg directive.
+func (cmd *TypedCommand[Args]) commandArguments() []commandArgument {
+	st, valid := utils.DerefType(&cmd.Args)
+	if !valid {
+		return nil
+	}
+	args := []commandArgument{}
+	for i := 0; i < st.NumField(); i++ {
+		field := st.Field(i)
+		split := strings.SplitN(field.Tag.Get(FieldTag), "=", 2)
+		if len(split) != 2 || split[0] != ArgDirective {
+			continue
+		}
+		arg := commandArgument{t: field.Type, named: true, trailing: field.Type.Kind() == reflect.Slice}
+		arg.name, arg.placeholder = parseArgDirective(split[1])
+		args = append(args, arg)
+	}
+	return args
+}
+
+// parseArgDirective parses the arg directive into the name of the argument and its placeholder in
+// the usage. The format of an arg directive is <name>, optionally followed by the
+// ,placeholder=<NAME> option.
+func parseArgDirective(directive string) (name string, placeholder string) {
+	directive, placeholder, _ = cutDirectiveOption(directive, "placeholder")
+	return unquoteDirective(directive), placeholder
+}
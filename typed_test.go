@@ -0,0 +1,94 @@
+package commander_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/apourchet/commander"
+	"github.com/stretchr/testify/require"
+)
+
+type GreetArgs struct {
+	Greeting string   `commander:"flag=greeting,The greeting to use"`
+	Name     string   `commander:"arg=name"`
+	Times    int      `commander:"arg=times"`
+	Others   []string `commander:"arg=others"`
+}
+
+type TypedApplication struct {
+	Greet interface{} `commander:"subcommand=greet,Greets people"`
+}
+
+func TestTypedCommand(t *testing.T) {
+	var received GreetArgs
+	newApp := func() *TypedApplication {
+		return &TypedApplication{
+			Greet: commander.Command(func(args GreetArgs) error {
+				received = args
+				if args.Name == "nobody" {
+					return errTest
+				}
+				return nil
+			}),
+		}
+	}
+
+	t.Run("binds_flags_and_arguments", func(t *testing.T) {
+		err := commander.New().RunCLI(newApp(), []string{"greet", "--greeting", "hi", "bob", "3", "alice", "eve"})
+		require.NoError(t, err)
+		require.Equal(t, GreetArgs{Greeting: "hi", Name: "bob", Times: 3, Others: []string{"alice", "eve"}}, received)
+	})
+
+	t.Run("application_error", func(t *testing.T) {
+		err := commander.New().RunCLI(newApp(), []string{"greet", "nobody", "1"})
+		require.Equal(t, errTest, err)
+	})
+
+	t.Run("bad_arguments", func(t *testing.T) {
+		err := commander.New().RunCLI(newApp(), []string{"greet", "bob"})
+		require.Error(t, err)
+
+		err = commander.New().RunCLI(newApp(), []string{"greet", "bob", "three"})
+		require.Error(t, err)
+	})
+
+	t.Run("too_many_arguments", func(t *testing.T) {
+		app := &struct {
+			Cmd interface{} `commander:"subcommand=cmd"`
+		}{
+			Cmd: commander.Command(func(args struct {
+				Name string `commander:"arg=name"`
+			}) error {
+				return nil
+			}),
+		}
+		err := commander.New().RunCLI(app, []string{"cmd", "a", "b"})
+		require.Error(t, err)
+	})
+}
+
+type ReadArgs struct {
+	Output string   `commander:"flag,name=output,placeholder=FILE,usage=Where to write the result"`
+	Format string   `commander:"arg=format,placeholder=FORMAT"`
+	Files  []string `commander:"arg=files,placeholder=FILE"`
+}
+
+func TestTypedCommandPlaceholders(t *testing.T) {
+	var received ReadArgs
+	app := commander.Command(func(args ReadArgs) error {
+		received = args
+		return nil
+	})
+
+	var out bytes.Buffer
+	cmd := commander.New()
+	cmd.Stdout = &out
+	cmd.Stdin = strings.NewReader("out.txt\n1\n\njson\na.txt b.txt\n")
+	require.NoError(t, cmd.RunWizard(app))
+	require.Equal(t, ReadArgs{Output: "out.txt", Format: "json", Files: []string{"a.txt", "b.txt"}}, received)
+	require.Contains(t, out.String(), "FORMAT: FORMAT is required\nFORMAT: FILE: ")
+
+	usage := cmd.Usage(app)
+	require.Contains(t, usage, "  -output FILE\n    \tWhere to write the result (default: \"out.txt\")\n")
+}
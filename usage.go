@@ -3,7 +3,11 @@ package commander
 import (
 	"bytes"
 	"fmt"
+	"math"
+	"reflect"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/apourchet/commander/utils"
 )
@@ -23,35 +27,36 @@ func (commander Commander) UsageWithCommand(app interface{}, cmd string) string
 // NamedUsage returns the usage of the CLI application with a custom name at the top.
 func (commander Commander) NamedUsage(app interface{}, appname string) string {
 	flagset, _ := commander.GetFlagSet(app, appname)
-	return usageWithFlagset(app, flagset)
+	return commander.usageWithFlagset(app, flagset)
 }
 
 // NamedUsageWithCommand returns the usage of this application given the command passed in, with
 // a custom name at the top.
 func (commander Commander) NamedUsageWithCommand(app interface{}, appname string, cmd string) string {
 	flagset, _ := commander.GetFlagSetWithCommand(app, appname, cmd)
-	return usageWithFlagset(app, flagset)
+	return commander.usageWithFlagset(app, flagset)
 }
 
 // PrintUsage prints the usage of the application given to the io.Writer specified; unless the
 // Commander fails to get the usage for this application.
 func (commander Commander) PrintUsage(app interface{}, appname string) {
 	usage := commander.NamedUsage(app, appname)
-	fmt.Fprintf(commander.UsageOutput, usage)
+	fmt.Fprint(commander.UsageOutput, usage)
 }
 
 // PrintUsageWithCommand prints the usage of the application like PrintUsage but for the specific
 // subcommand provided.
 func (commander Commander) PrintUsageWithCommand(app interface{}, appname string, cmd string) {
 	usage := commander.NamedUsageWithCommand(app, appname, cmd)
-	fmt.Fprintf(commander.UsageOutput, usage)
+	fmt.Fprint(commander.UsageOutput, usage)
 }
 
-func usageWithFlagset(app interface{}, flagset *FlagSet) string {
+func (commander Commander) usageWithFlagset(app interface{}, flagset *FlagSet) string {
 	var buf bytes.Buffer
 	if flagset != nil {
 		flagset.SetOutput(&buf)
 		flagset.Usage()
+		writeEnvironment(&buf, flagset)
 	}
 	// Then print subcommands
 	st, valid := utils.DerefType(app)
@@ -60,6 +65,18 @@ func usageWithFlagset(app interface{}, flagset *FlagSet) string {
 	}
 
 	directives := map[string]string{}
+	order, declared := map[string]int{}, map[string]int{}
+	if mountsPlugins(st) {
+		for _, name := range registeredPluginNames() {
+			directives[name] = ""
+		}
+	}
+	for _, name := range mountedNames(app) {
+		directives[name] = ""
+	}
+	for _, name := range dynamicSubcommandNames(app) {
+		directives[name] = ""
+	}
 	for i := 0; i < st.NumField(); i++ {
 		field := st.Field(i)
 		if alias, ok := field.Tag.Lookup(FieldTag); ok && alias != "" {
@@ -71,12 +88,19 @@ func usageWithFlagset(app interface{}, flagset *FlagSet) string {
 				continue
 			}
 
-			cmd, newdesc := parseSubcommandDirective(split[1])
+			directive, _ := parseSubcommandDirective(split[1])
+			cmd, newdesc := directive.cmd, directive.usageDescription()
 			if split[0] == FlagStructDirective {
 				if found, _ := hasCommand(app, cmd); !found {
 					continue
 				}
 			}
+			if _, found := declared[cmd]; !found {
+				declared[cmd] = i
+			}
+			if directive.order != 0 {
+				order[cmd] = directive.order
+			}
 
 			if desc, found := directives[cmd]; !found || desc == "" {
 				directives[cmd] = newdesc
@@ -84,12 +108,11 @@ func usageWithFlagset(app interface{}, flagset *FlagSet) string {
 		}
 	}
 
-	if len(directives) == 0 {
-		return buf.String()
+	if len(directives) > 0 {
+		fmt.Fprintf(&buf, "\nSub-Commands:\n")
 	}
-
-	fmt.Fprintf(&buf, "\nSub-Commands:\n")
 	cmds := sortKeys(directives)
+	orderNames(cmds, order, declared, commander.DeclarationOrder)
 	for _, cmd := range cmds {
 		desc := "No description for this subcommand"
 		if directives[cmd] != "" {
@@ -103,5 +126,203 @@ func usageWithFlagset(app interface{}, flagset *FlagSet) string {
 		fmt.Fprintf(&buf, "  %v  |  %v\n", cmd, desc)
 	}
 
+	if provider, ok := app.(HelpTopicsProvider); ok && len(provider.GetHelpTopics()) > 0 {
+		topics := provider.GetHelpTopics()
+		fmt.Fprintf(&buf, "\nTopics:\n")
+		for _, topic := range sortKeys(topics) {
+			summary := strings.SplitN(strings.TrimSpace(topics[topic]), "\n", 2)[0]
+			fmt.Fprintf(&buf, "  %v  |  %v\n", topic, summary)
+		}
+	}
+
 	return buf.String()
 }
+
+// usageWidth is the width that the text of the usage is wrapped to.
+const usageWidth = 80
+
+// wrapText wraps the paragraphs of the text to the width given, indenting each line. Paragraphs are
+// separated by blank lines, and the lines that start with a space are kept as they are.
+func wrapText(text string, indent string, width int) string {
+	lines, words := []string{}, []string{}
+	flush := func() {
+		line := indent
+		for _, word := range words {
+			if line != indent && len(line)+1+len(word) > width {
+				lines, line = append(lines, line), indent
+			}
+			if line != indent {
+				line += " "
+			}
+			line += word
+		}
+		if len(words) > 0 {
+			lines = append(lines, line)
+		}
+		words = nil
+	}
+
+	for _, line := range strings.Split(strings.Trim(text, "\n"), "\n") {
+		switch {
+		case strings.TrimSpace(line) == "":
+			flush()
+			if len(lines) > 0 && lines[len(lines)-1] != "" {
+				lines = append(lines, "")
+			}
+		case strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t"):
+			flush()
+			lines = append(lines, indent+line)
+		default:
+			words = append(words, strings.Fields(line)...)
+		}
+	}
+	flush()
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+}
+
+// orderNames sorts the names of the entries of a usage, which are sorted by name already. The
+// names with an explicit order come first, by increasing order. The others follow by name, or by
+// declaration index if byDeclaration is set, the names without a declaration index coming last.
+func orderNames(names []string, order map[string]int, declared map[string]int, byDeclaration bool) {
+	rank := func(name string) (int, int) {
+		index, found := declared[name]
+		if !byDeclaration {
+			index = 0
+		} else if !found {
+			index = math.MaxInt
+		}
+		if order[name] != 0 {
+			return order[name], index
+		}
+		return math.MaxInt, index
+	}
+	sort.SliceStable(names, func(i, j int) bool {
+		iorder, iindex := rank(names[i])
+		jorder, jindex := rank(names[j])
+		if iorder != jorder {
+			return iorder < jorder
+		}
+		return iindex < jindex
+	})
+}
+
+// writeEnvironment writes the environment variables that the flagset reads, from the env
+// directives and from the env options of its flags.
+func writeEnvironment(buf *bytes.Buffer, flagset *FlagSet) {
+	variables := map[string]string{}
+	for name, target := range flagset.targets {
+		if target.env != "" {
+			variables[target.env] = "Default of -" + name
+		}
+	}
+	for _, target := range flagset.envs {
+		if target.env != "" {
+			variables[target.env] = target.usage
+		}
+	}
+	if len(variables) == 0 {
+		return
+	}
+	fmt.Fprintf(buf, "\nEnvironment:\n")
+	for _, variable := range sortKeys(variables) {
+		desc := "No description for this variable"
+		if variables[variable] != "" {
+			desc = variables[variable]
+		}
+		fmt.Fprintf(buf, "  %v  |  %v\n", variable, desc)
+	}
+}
+
+// commandSynopsis returns the synopsis of the command, like "petstore manage copy <src> <dst>".
+func commandSynopsis(app interface{}, appname string, cmd string) string {
+	words := []string{appname, cmd}
+	for _, arg := range commandArguments(app, cmd) {
+		if arg.placeholder != "" && arg.trailing {
+			words = append(words, arg.placeholder+"...")
+		} else if arg.placeholder != "" {
+			words = append(words, arg.placeholder)
+		} else if arg.trailing && arg.t.Kind() == reflect.Map && !arg.named {
+			words = append(words, fmt.Sprintf("[<%v>=<%v>...]", argumentTypeName(arg.t.Key()), argumentTypeName(arg.t.Elem())))
+		} else if arg.trailing {
+			words = append(words, fmt.Sprintf("[<%v>...]", arg.name))
+		} else {
+			words = append(words, fmt.Sprintf("<%v>", arg.name))
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// commandArgument is a parameter of a command that is given on the command line.
+type commandArgument struct {
+	name string
+	t    reflect.Type
+
+	// named is true if the name comes from the ArgumentNamesProvider of the application rather
+	// than from the type, and trailing if the argument collects the extra arguments of the command.
+	named    bool
+	trailing bool
+
+	// placeholder stands for the argument in the synopsis, like FILE in "read FILE...".
+	placeholder string
+}
+
+// argumentsDescriber is implemented by the applications whose default command takes its arguments
+// in another form than its parameters, like typed commands.
+type argumentsDescriber interface {
+	commandArguments() []commandArgument
+}
+
+// commandArguments returns the parameters of the command that are given on the command line,
+// leaving out the parameters that receive injected values. The arguments are named by the
+// ArgumentNamesProvider of the application, or after their types otherwise. The arguments of
+// typed commands come from the arg directives of their arguments struct instead.
+func commandArguments(app interface{}, cmd string) []commandArgument {
+	method, err := getMethod(app, cmd)
+	if err != nil {
+		return nil
+	} else if describer, ok := app.(argumentsDescriber); ok && method.Name == DefaultCommand {
+		return describer.commandArguments()
+	}
+
+	params := []reflect.Type{}
+	for i := 1; i < method.Type.NumIn(); i++ {
+		t := method.Type.In(i)
+		if len(params) == 0 && (t == contextType || t == readerType || t == writerType) {
+			continue
+		}
+		params = append(params, t)
+	}
+
+	var names []string
+	if provider, ok := app.(ArgumentNamesProvider); ok {
+		names = provider.GetArgumentNames(cmd)
+	}
+	args := make([]commandArgument, len(params))
+	for i, t := range params {
+		args[i] = commandArgument{name: argumentTypeName(t), t: t}
+		if i < len(names) {
+			args[i].name, args[i].named = names[i], true
+		}
+		args[i].trailing = i == len(params)-1 && !utils.IsRawMessage(t) &&
+			(t.Kind() == reflect.Slice || t.Kind() == reflect.Map)
+	}
+	return args
+}
+
+// argumentTypeName returns the name of the type of an argument, as shown in synopses.
+func argumentTypeName(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr || (t.Kind() == reflect.Slice && t.Name() == "" && !utils.IsRawMessage(t)) {
+		t = t.Elem()
+	}
+	switch {
+	case t == reflect.TypeOf(time.Time{}):
+		return "time"
+	case utils.IsRawMessage(t):
+		return "json"
+	case t.PkgPath() == "math/big":
+		return t.String()
+	case t.Name() == "":
+		return t.Kind().String()
+	}
+	return strings.ToLower(t.Name())
+}
@@ -3,8 +3,11 @@ package utils
 import (
 	"encoding/json"
 	"fmt"
+	"math"
+	"math/big"
 	"reflect"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -41,8 +44,21 @@ func Stringify(val interface{}) (string, error) {
 }
 
 // StringifyValue returns the string representation of the value given. It functions like fmt.Printf("%v")
-// except for slices and maps; where it json stringifies them.
+// except for slices and maps; where it json stringifies them. Durations are written like "4h0m0s" so
+// that ParseString can read them back.
 func StringifyValue(v reflect.Value) (string, error) {
+	if v.IsValid() && v.Type() == timeType {
+		return v.Interface().(time.Time).Format(time.RFC3339Nano), nil
+	} else if v.IsValid() && v.Type() == durationType {
+		return v.Interface().(time.Duration).String(), nil
+	} else if v.IsValid() && v.Type() == bigIntType {
+		i := v.Interface().(big.Int)
+		return i.String(), nil
+	} else if v.IsValid() && v.Type() == bigFloatType {
+		f := v.Interface().(big.Float)
+		return f.Text('g', -1), nil
+	}
+
 	switch v.Kind() {
 	case reflect.Ptr:
 		return StringifyValue(v.Elem())
@@ -54,6 +70,10 @@ func StringifyValue(v reflect.Value) (string, error) {
 		return fmt.Sprintf("%v", v.Uint()), nil
 	case reflect.Float32, reflect.Float64:
 		return fmt.Sprintf("%v", v.Float()), nil
+	case reflect.Complex64:
+		return strconv.FormatComplex(v.Complex(), 'g', -1, 64), nil
+	case reflect.Complex128:
+		return strconv.FormatComplex(v.Complex(), 'g', -1, 128), nil
 	case reflect.String:
 		return fmt.Sprintf("%v", v.String()), nil
 	case reflect.Slice, reflect.Map:
@@ -107,9 +127,67 @@ func SetField(obj interface{}, fieldname, value string) error {
 	return nil
 }
 
+var (
+	durationType   = reflect.TypeOf(time.Duration(0))
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+	bigIntType     = reflect.TypeOf(big.Int{})
+	bigFloatType   = reflect.TypeOf(big.Float{})
+)
+
+// IsRawMessage returns true if the type is json.RawMessage. Although they are slices, raw messages
+// are parsed from a single JSON string.
+func IsRawMessage(t reflect.Type) bool {
+	return t == rawMessageType
+}
+
+// TimeLayouts are the layouts that ParseString tries in order when parsing a time.Time.
+var TimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
 // ParseString parses the string into a value depending on the type that gets passed in.
 // time.Duration is handled separately because of the fact that its an int64 with some fancy parsing involved.
+// time.Time values are parsed using one of the TimeLayouts, and json.RawMessage values are kept as
+// they are once they are validated to be JSON. Slices and maps are parsed from JSON, from flat
+// YAML collections or from bare comma-separated values, like "a,b" and "a=1,b=2".
 func ParseString(t reflect.Type, value string) (reflect.Value, error) {
+	switch t {
+	case rawMessageType:
+		if !json.Valid([]byte(value)) {
+			return reflect.ValueOf(nil), fmt.Errorf("Failed to parse string to %v: invalid JSON %q", t, value)
+		}
+		return reflect.ValueOf(json.RawMessage(value)), nil
+	case durationType:
+		dur, err := time.ParseDuration(value)
+		if err != nil {
+			return reflect.ValueOf(nil), fmt.Errorf("Failed to parse string to %T: %v", dur, err)
+		}
+		return reflect.ValueOf(dur), nil
+	case timeType:
+		for _, layout := range TimeLayouts {
+			if parsed, err := time.Parse(layout, value); err == nil {
+				return reflect.ValueOf(parsed), nil
+			}
+		}
+		return reflect.ValueOf(nil), fmt.Errorf("Failed to parse string to %v: unknown time format %q", t, value)
+	case bigIntType:
+		i, err := parseBigInt(value)
+		if err != nil {
+			return reflect.ValueOf(nil), fmt.Errorf("Failed to parse string to %v: %v", t, err)
+		}
+		return reflect.ValueOf(*i), nil
+	case bigFloatType:
+		f, err := parseBigFloat(value)
+		if err != nil {
+			return reflect.ValueOf(nil), fmt.Errorf("Failed to parse string to %v: %v", t, err)
+		}
+		return reflect.ValueOf(*f), nil
+	}
+
 	switch t.Kind() {
 	case reflect.Ptr:
 		subval, err := ParseString(t.Elem(), value)
@@ -120,7 +198,7 @@ func ParseString(t reflect.Type, value string) (reflect.Value, error) {
 		val.Elem().Set(subval)
 		return val, nil
 	case reflect.Bool:
-		b, err := strconv.ParseBool(value)
+		b, err := parseBool(value)
 		if err != nil {
 			return reflect.ValueOf(nil), fmt.Errorf("Failed to parse string to %T: %v", b, err)
 		}
@@ -128,91 +206,97 @@ func ParseString(t reflect.Type, value string) (reflect.Value, error) {
 	case reflect.String:
 		return reflect.ValueOf(value), nil
 	case reflect.Int:
-		i, err := strconv.ParseInt(value, 10, 64)
+		i, err := parseInt(value, 64)
 		if err != nil {
 			return reflect.ValueOf(nil), fmt.Errorf("Failed to parse string to %T: %v", i, err)
 		}
 		return reflect.ValueOf(int(i)), nil
 	case reflect.Int8:
-		i, err := strconv.ParseInt(value, 10, 8)
+		i, err := parseInt(value, 8)
 		if err != nil {
 			return reflect.ValueOf(nil), fmt.Errorf("Failed to parse string to %T: %v", i, err)
 		}
 		return reflect.ValueOf(int8(i)), nil
 	case reflect.Int16:
-		i, err := strconv.ParseInt(value, 10, 16)
+		i, err := parseInt(value, 16)
 		if err != nil {
 			return reflect.ValueOf(nil), fmt.Errorf("Failed to parse string to %T: %v", i, err)
 		}
 		return reflect.ValueOf(int16(i)), nil
 	case reflect.Int32:
-		i, err := strconv.ParseInt(value, 10, 32)
+		i, err := parseInt(value, 32)
 		if err != nil {
 			return reflect.ValueOf(nil), fmt.Errorf("Failed to parse string to %T: %v", i, err)
 		}
 		return reflect.ValueOf(int32(i)), nil
 	case reflect.Int64:
-		i, err := strconv.ParseInt(value, 10, 64)
-		if err == nil {
-			return reflect.ValueOf(int64(i)), nil
-		}
-		dur, err := time.ParseDuration(value)
+		i, err := parseInt(value, 64)
 		if err != nil {
-			return reflect.ValueOf(nil), fmt.Errorf("Failed to parse string to %T or %T: %v", i, dur, err)
+			return reflect.ValueOf(nil), fmt.Errorf("Failed to parse string to %T: %v", i, err)
 		}
-		return reflect.ValueOf(dur), nil
+		return reflect.ValueOf(int64(i)), nil
 	case reflect.Uint:
-		i, err := strconv.ParseUint(value, 10, 64)
+		i, err := parseUint(value, 64)
 		if err != nil {
 			return reflect.ValueOf(nil), fmt.Errorf("Failed to parse string to %T: %v", i, err)
 		}
 		return reflect.ValueOf(uint(i)), nil
 	case reflect.Uint8:
-		i, err := strconv.ParseUint(value, 10, 8)
+		i, err := parseUint(value, 8)
 		if err != nil {
 			return reflect.ValueOf(nil), fmt.Errorf("Failed to parse string to %T: %v", i, err)
 		}
 		return reflect.ValueOf(uint8(i)), nil
 	case reflect.Uint16:
-		i, err := strconv.ParseUint(value, 10, 16)
+		i, err := parseUint(value, 16)
 		if err != nil {
 			return reflect.ValueOf(nil), fmt.Errorf("Failed to parse string to %T: %v", i, err)
 		}
 		return reflect.ValueOf(uint16(i)), nil
 	case reflect.Uint32:
-		i, err := strconv.ParseUint(value, 10, 32)
+		i, err := parseUint(value, 32)
 		if err != nil {
 			return reflect.ValueOf(nil), fmt.Errorf("Failed to parse string to %T: %v", i, err)
 		}
 		return reflect.ValueOf(uint32(i)), nil
 	case reflect.Uint64:
-		i, err := strconv.ParseUint(value, 10, 64)
+		i, err := parseUint(value, 64)
 		if err != nil {
 			return reflect.ValueOf(nil), fmt.Errorf("Failed to parse string to %T: %v", i, err)
 		}
 		return reflect.ValueOf(uint64(i)), nil
 	case reflect.Float32:
-		f, err := strconv.ParseFloat(value, 32)
+		f, err := parseFloat(value, 32)
 		if err != nil {
 			return reflect.ValueOf(nil), fmt.Errorf("Failed to parse string to %T: %v", f, err)
 		}
 		return reflect.ValueOf(float32(f)), nil
 	case reflect.Float64:
-		f, err := strconv.ParseFloat(value, 64)
+		f, err := parseFloat(value, 64)
 		if err != nil {
 			return reflect.ValueOf(nil), fmt.Errorf("Failed to parse string to %T: %v", f, err)
 		}
 		return reflect.ValueOf(float64(f)), nil
+	case reflect.Complex64:
+		c, err := strconv.ParseComplex(value, 64)
+		if err != nil {
+			return reflect.ValueOf(nil), fmt.Errorf("Failed to parse string to %T: %v", c, err)
+		}
+		return reflect.ValueOf(complex64(c)), nil
+	case reflect.Complex128:
+		c, err := strconv.ParseComplex(value, 128)
+		if err != nil {
+			return reflect.ValueOf(nil), fmt.Errorf("Failed to parse string to %T: %v", c, err)
+		}
+		return reflect.ValueOf(c), nil
 	case reflect.Slice:
-		s := []string{}
-		err := json.Unmarshal([]byte(value), &s)
+		s, err := parseList(value)
 		if err != nil {
 			return reflect.ValueOf(nil), fmt.Errorf("Failed to parse string to %T: %v", s, err)
 		}
 		return reflect.ValueOf(s), nil
 	case reflect.Map:
-		m := map[string]string{}
-		err := json.Unmarshal([]byte(value), &m)
+		m, err := parseMap(value)
 		if err != nil {
 			return reflect.ValueOf(nil), fmt.Errorf("Failed to parse string to %T: %v", m, err)
 		}
@@ -220,3 +304,215 @@ func ParseString(t reflect.Type, value string) (reflect.Value, error) {
 	}
 	return reflect.ValueOf(nil), fmt.Errorf("Unsupported type: %v", t)
 }
+
+// parseBool parses a boolean like strconv.ParseBool, also accepting yes, no, on, off, y and n in
+// any case.
+func parseBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "yes", "y", "on":
+		return true, nil
+	case "no", "n", "off":
+		return false, nil
+	}
+	return strconv.ParseBool(strings.ToLower(value))
+}
+
+// parseInt parses an integer written like in Go source: in decimal, hexadecimal (0x1F), octal
+// (0o17) or binary (0b101), with underscores between digits (1_000_000). Whole numbers in
+// scientific notation (1e6) are accepted too. Unlike in Go source, leading zeros do not make the
+// number octal.
+func parseInt(value string, bitSize int) (int64, error) {
+	i, err := strconv.ParseInt(trimLeadingZeros(value), 0, bitSize)
+	if whole, ok := scientificInteger(value, err); ok {
+		return strconv.ParseInt(whole, 10, bitSize)
+	}
+	return i, err
+}
+
+// parseUint parses an unsigned integer like parseInt.
+func parseUint(value string, bitSize int) (uint64, error) {
+	i, err := strconv.ParseUint(trimLeadingZeros(value), 0, bitSize)
+	if whole, ok := scientificInteger(value, err); ok {
+		return strconv.ParseUint(whole, 10, bitSize)
+	}
+	return i, err
+}
+
+// parseFloat parses a floating-point number written like in Go source, or an integer written like
+// parseInt parses them, such as 0x1F.
+func parseFloat(value string, bitSize int) (float64, error) {
+	f, err := strconv.ParseFloat(value, bitSize)
+	if err != nil {
+		if i, ierr := parseInt(value, 64); ierr == nil {
+			return float64(i), nil
+		}
+	}
+	return f, err
+}
+
+// parseBigInt parses an integer of any size written like parseInt parses them.
+func parseBigInt(value string) (*big.Int, error) {
+	if i, ok := new(big.Int).SetString(trimLeadingZeros(value), 0); ok {
+		return i, nil
+	}
+	if strings.ContainsAny(value, "eE") && !strings.HasPrefix(strings.ToLower(strings.TrimLeft(value, "+-")), "0x") {
+		if r, ok := new(big.Rat).SetString(value); ok && r.IsInt() {
+			return r.Num(), nil
+		}
+	}
+	return nil, fmt.Errorf("invalid integer %q", value)
+}
+
+// parseBigFloat parses a floating-point number of any size written like parseFloat parses them,
+// with a precision large enough to keep all of its digits.
+func parseBigFloat(value string) (*big.Float, error) {
+	prec := uint(len(value)) * 4
+	if prec < 64 {
+		prec = 64
+	}
+	f, _, err := big.ParseFloat(value, 0, prec, big.ToNearestEven)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %q", value)
+	}
+	return f, nil
+}
+
+// trimLeadingZeros removes the leading zeros of a decimal integer, so that it is not parsed as an
+// octal one.
+func trimLeadingZeros(value string) string {
+	sign, digits := "", value
+	if strings.HasPrefix(digits, "-") || strings.HasPrefix(digits, "+") {
+		sign, digits = digits[:1], digits[1:]
+	}
+	for len(digits) > 1 && digits[0] == '0' && digits[1] >= '0' && digits[1] <= '9' {
+		digits = digits[1:]
+	}
+	return sign + digits
+}
+
+// scientificInteger returns the decimal form of the value if it failed to parse as an integer
+// because it is written in scientific notation, and is a whole number.
+func scientificInteger(value string, err error) (string, bool) {
+	if numErr, ok := err.(*strconv.NumError); !ok || numErr.Err != strconv.ErrSyntax || !strings.ContainsAny(value, "eE") ||
+		strings.HasPrefix(strings.TrimLeft(value, "+-"), "0x") || strings.HasPrefix(strings.TrimLeft(value, "+-"), "0X") {
+		return "", false
+	}
+	f, ferr := strconv.ParseFloat(value, 64)
+	if ferr != nil || f != math.Trunc(f) || math.IsInf(f, 0) {
+		return "", false
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64), true
+}
+
+// parseList parses a list of strings written in JSON, as a flat YAML sequence in flow style
+// ("[a, b]") or in block style ("- a" lines), or as bare comma-separated values ("a,b"). Any other
+// value, like "hello, world", is a plain string that makes up the single item of the list.
+func parseList(value string) ([]string, error) {
+	s := []string{}
+	trimmed := strings.TrimSpace(value)
+	items := []string{trimmed}
+	if err := json.Unmarshal([]byte(trimmed), &s); err == nil {
+		return s, nil
+	} else if strings.HasPrefix(trimmed, "[") {
+		if !strings.HasSuffix(trimmed, "]") {
+			return nil, err
+		}
+		trimmed = strings.TrimSuffix(strings.TrimPrefix(trimmed, "["), "]")
+		items = strings.Split(trimmed, ",")
+	} else if strings.HasPrefix(trimmed, "- ") || trimmed == "-" {
+		for _, line := range nonEmptyLines(trimmed) {
+			if line != "-" && !strings.HasPrefix(line, "- ") {
+				return nil, fmt.Errorf("expected a YAML sequence item, got %q", line)
+			}
+			s = append(s, unquoteScalar(strings.TrimPrefix(line, "-")))
+		}
+		return s, nil
+	} else if split, bare := splitBareList(trimmed); bare {
+		items = split
+	}
+
+	if strings.TrimSpace(trimmed) == "" {
+		return s, nil
+	}
+	for _, item := range items {
+		s = append(s, unquoteScalar(item))
+	}
+	return s, nil
+}
+
+// parseMap parses a map of strings written in JSON, as a flat YAML mapping in flow style
+// ("{a: 1, b: 2}") or in block style ("a: 1" lines), or as bare comma-separated key=value pairs
+// ("a=1,b=2"). Any other value with an equal sign, like "msg=hello, world", is a single pair.
+func parseMap(value string) (map[string]string, error) {
+	m := map[string]string{}
+	trimmed := strings.TrimSpace(value)
+	var entries []string
+	separator := ":"
+	if err := json.Unmarshal([]byte(trimmed), &m); err == nil {
+		return m, nil
+	} else if strings.HasPrefix(trimmed, "{") {
+		if !strings.HasSuffix(trimmed, "}") {
+			return nil, err
+		}
+		entries = strings.Split(strings.TrimSuffix(strings.TrimPrefix(trimmed, "{"), "}"), ",")
+	} else if strings.Contains(trimmed, "\n") || !strings.Contains(trimmed, "=") {
+		entries = nonEmptyLines(trimmed)
+	} else if items, bare := splitBareList(trimmed); bare {
+		entries, separator = items, "="
+	} else {
+		entries, separator = []string{trimmed}, "="
+	}
+
+	for _, entry := range entries {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		split := strings.SplitN(entry, separator, 2)
+		if len(split) != 2 {
+			return nil, fmt.Errorf("expected a key%vvalue entry, got %q", separator, strings.TrimSpace(entry))
+		}
+		m[unquoteScalar(split[0])] = unquoteScalar(split[1])
+	}
+	return m, nil
+}
+
+// splitBareList splits the value on its commas if it is a bare comma-separated list, that is if none
+// of its commas has spaces around it.
+func splitBareList(value string) ([]string, bool) {
+	items := strings.Split(value, ",")
+	if len(items) == 1 {
+		return nil, false
+	}
+	for _, item := range items {
+		if item != strings.TrimSpace(item) {
+			return nil, false
+		}
+	}
+	return items, true
+}
+
+// nonEmptyLines returns the lines of the string that are not blank, trimmed of their spaces.
+func nonEmptyLines(str string) []string {
+	lines := []string{}
+	for _, line := range strings.Split(str, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// unquoteScalar trims the spaces around a YAML scalar and removes its quotes, if it has any.
+func unquoteScalar(scalar string) string {
+	scalar = strings.TrimSpace(scalar)
+	if len(scalar) < 2 {
+		return scalar
+	} else if scalar[0] == '"' && scalar[len(scalar)-1] == '"' {
+		if unquoted, err := strconv.Unquote(scalar); err == nil {
+			return unquoted
+		}
+	} else if scalar[0] == '\'' && scalar[len(scalar)-1] == '\'' {
+		return strings.Replace(scalar[1:len(scalar)-1], "''", "'", -1)
+	}
+	return scalar
+}
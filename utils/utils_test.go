@@ -1,6 +1,8 @@
 package utils_test
 
 import (
+	"encoding/json"
+	"math/big"
 	"reflect"
 	"testing"
 	"time"
@@ -165,3 +167,197 @@ func TestSetGetField(t *testing.T) {
 	}
 	require.Equal(t, expected, obj)
 }
+
+func TestParseStringTime(t *testing.T) {
+	table := []struct {
+		value    string
+		expected time.Time
+	}{
+		{"2024-01-02", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"2024-01-02 03:04:05", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"2024-01-02T03:04:05", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"2024-01-02T03:04:05Z", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+	}
+
+	for _, test := range table {
+		v, err := utils.ParseString(reflect.TypeOf(time.Time{}), test.value)
+		require.NoError(t, err)
+		require.True(t, test.expected.Equal(v.Interface().(time.Time)))
+	}
+
+	_, err := utils.ParseString(reflect.TypeOf(time.Time{}), "yesterday")
+	require.Error(t, err)
+
+	str, err := utils.Stringify(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	require.NoError(t, err)
+	require.Equal(t, "2024-01-02T03:04:05Z", str)
+}
+
+func TestParseStringDuration(t *testing.T) {
+	v, err := utils.ParseString(reflect.TypeOf(time.Duration(0)), "30s")
+	require.NoError(t, err)
+	require.Equal(t, 30*time.Second, v.Interface())
+
+	_, err = utils.ParseString(reflect.TypeOf(time.Duration(0)), "30")
+	require.Error(t, err)
+
+	v, err = utils.ParseString(reflect.TypeOf(int64(0)), "30")
+	require.NoError(t, err)
+	require.Equal(t, int64(30), v.Interface())
+}
+
+func TestParseStringBools(t *testing.T) {
+	values := map[string]bool{
+		"true": true, "TRUE": true, "1": true, "t": true, "yes": true, "Y": true, "On": true,
+		"false": false, "F": false, "0": false, "no": false, "N": false, "OFF": false,
+	}
+	for value, expected := range values {
+		v, err := utils.ParseString(reflect.TypeOf(false), value)
+		require.NoError(t, err, value)
+		require.Equal(t, expected, v.Interface(), value)
+	}
+
+	for _, value := range []string{"", "maybe", "yess", "2"} {
+		_, err := utils.ParseString(reflect.TypeOf(false), value)
+		require.Error(t, err, value)
+	}
+}
+
+func TestParseStringNumbers(t *testing.T) {
+	values := []struct {
+		value    string
+		expected interface{}
+	}{
+		{"1_000_000", 1000000},
+		{"1e6", 1000000},
+		{"-2.5E3", -2500},
+		{"0x1F", 31},
+		{"0o17", 15},
+		{"0b101", 5},
+		{"010", 10},
+		{"-007", -7},
+		{"1e3", uint16(1000)},
+		{"0xff", uint8(255)},
+		{"1_000.5", 1000.5},
+		{"1e-3", 0.001},
+		{"0x1F", 31.0},
+		{"2.5", float32(2.5)},
+	}
+	for _, test := range values {
+		v, err := utils.ParseString(reflect.TypeOf(test.expected), test.value)
+		require.NoError(t, err, test.value)
+		require.Equal(t, test.expected, v.Interface(), test.value)
+	}
+
+	invalid := []struct {
+		value string
+		t     interface{}
+	}{
+		{"1.5", 0},
+		{"1e-3", 0},
+		{"1e100", int64(0)},
+		{"0x100", uint8(0)},
+		{"1__0", 0},
+		{"-1", uint(0)},
+	}
+	for _, test := range invalid {
+		_, err := utils.ParseString(reflect.TypeOf(test.t), test.value)
+		require.Error(t, err, test.value)
+	}
+}
+
+func TestParseStringBigAndComplex(t *testing.T) {
+	huge, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	values := []struct {
+		value    string
+		t        reflect.Type
+		expected string
+	}{
+		{"123456789012345678901234567890", reflect.TypeOf(&big.Int{}), "123456789012345678901234567890"},
+		{"-0x1_0000_0000_0000_0000", reflect.TypeOf(big.Int{}), "-18446744073709551616"},
+		{"0042", reflect.TypeOf(big.Int{}), "42"},
+		{"1e30", reflect.TypeOf(&big.Int{}), "1000000000000000000000000000000"},
+		{"3.14159265358979323846264338327950288", reflect.TypeOf(&big.Float{}), "3.14159265358979323846264338327950288"},
+		{"1_000.5", reflect.TypeOf(big.Float{}), "1000.5"},
+		{"1+2i", reflect.TypeOf(complex128(0)), "(1+2i)"},
+		{"-1.5i", reflect.TypeOf(complex64(0)), "(0-1.5i)"},
+	}
+	for _, test := range values {
+		v, err := utils.ParseString(test.t, test.value)
+		require.NoError(t, err, test.value)
+		str, err := utils.StringifyValue(v)
+		require.NoError(t, err, test.value)
+		require.Equal(t, test.expected, str, test.value)
+	}
+
+	v, err := utils.ParseString(reflect.TypeOf(&big.Int{}), "123456789012345678901234567890")
+	require.NoError(t, err)
+	require.Equal(t, 0, huge.Cmp(v.Interface().(*big.Int)))
+
+	invalid := []struct {
+		value string
+		t     reflect.Type
+	}{
+		{"1.5", reflect.TypeOf(big.Int{})},
+		{"1e-3", reflect.TypeOf(&big.Int{})},
+		{"abc", reflect.TypeOf(&big.Float{})},
+		{"1+2j", reflect.TypeOf(complex128(0))},
+	}
+	for _, test := range invalid {
+		_, err := utils.ParseString(test.t, test.value)
+		require.Error(t, err, test.value)
+	}
+}
+
+func TestParseStringRawMessage(t *testing.T) {
+	rawType := reflect.TypeOf(json.RawMessage{})
+	v, err := utils.ParseString(rawType, `{"nested": {"values": [1, 2]}}`)
+	require.NoError(t, err)
+	require.Equal(t, json.RawMessage(`{"nested": {"values": [1, 2]}}`), v.Interface())
+
+	_, err = utils.ParseString(rawType, `{"nested":`)
+	require.Error(t, err)
+}
+
+func TestParseStringCollections(t *testing.T) {
+	lists := map[string][]string{
+		`["a","b"]`:          {"a", "b"},
+		`[a, 'b c', "d"]`:    {"a", "b c", "d"},
+		"- a\n- b c\n":       {"a", "b c"},
+		"a,b,c":              {"a", "b", "c"},
+		"hello, world":       {"hello, world"},
+		"a,b , c":            {"a,b , c"},
+		"single":             {"single"},
+		"":                   {},
+		`[]`:                 {},
+		"- 'it''s'\n- \"x\"": {"it's", "x"},
+	}
+	for value, expected := range lists {
+		v, err := utils.ParseString(reflect.TypeOf([]string{}), value)
+		require.NoError(t, err, value)
+		require.Equal(t, expected, v.Interface(), value)
+	}
+
+	maps := map[string]map[string]string{
+		`{"a":"b"}`:            {"a": "b"},
+		`{a: b, 'c d': "e"}`:   {"a": "b", "c d": "e"},
+		"a: b\nc: d e\n":       {"a": "b", "c": "d e"},
+		"a=b,c=d":              {"a": "b", "c": "d"},
+		"msg=hello, world":     {"msg": "hello, world"},
+		"url: http://host:80/": {"url": "http://host:80/"},
+	}
+	for value, expected := range maps {
+		v, err := utils.ParseString(reflect.TypeOf(map[string]string{}), value)
+		require.NoError(t, err, value)
+		require.Equal(t, expected, v.Interface(), value)
+	}
+
+	for _, value := range []string{`["a"`, "- a\nb"} {
+		_, err := utils.ParseString(reflect.TypeOf([]string{}), value)
+		require.Error(t, err, value)
+	}
+	for _, value := range []string{`{"a":"b"`, "a: b\nc", "a=b,c"} {
+		_, err := utils.ParseString(reflect.TypeOf(map[string]string{}), value)
+		require.Error(t, err, value)
+	}
+}
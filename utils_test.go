@@ -1,6 +1,16 @@
 package commander_test
 
-import "fmt"
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
 
 type Application struct {
 	count          int
@@ -14,6 +24,12 @@ type Application struct {
 
 var errTest = fmt.Errorf("ERROR")
 
+// TestMain names the test binary like the applications whose usage the tests expect.
+func TestMain(m *testing.M) {
+	os.Args[0] = "CLI"
+	os.Exit(m.Run())
+}
+
 func (app *Application) OpOne(str string) error {
 	if str == "test" {
 		app.count++
@@ -42,6 +58,22 @@ func (app *Application) OpVariadic(name string, names []string) {
 	app.count += len(names)
 }
 
+func (app *Application) OpSum(nums []int) {
+	for _, num := range nums {
+		app.count += num
+	}
+}
+
+func (app *Application) OpSpread(first string, rest ...string) {
+	app.count += len(rest)
+}
+
+func (app *Application) OpSince(since time.Time, wait time.Duration) {
+	if since.Year() == 2024 && wait == 30*time.Second {
+		app.count++
+	}
+}
+
 type SubApplication struct {
 	count int
 
@@ -64,6 +96,12 @@ func (app *SubApplication) OpFour(m map[string]string) {
 	}
 }
 
+func (app *SubApplication) OpEnv(name string, env map[string]int) {
+	for _, value := range env {
+		app.count += value
+	}
+}
+
 type SubSubApplication struct {
 	count int
 }
@@ -127,3 +165,164 @@ func (app *Application3) GetCommandDescription(cmd string) string {
 	}
 	return ""
 }
+
+type TimeoutApplication struct {
+	Slow *SlowApplication    `commander:"subcommand=slow,Runs slow commands,timeout=10ms"`
+	Ctx  *ContextApplication `commander:"subcommand=ctx,Runs context commands,timeout=10ms"`
+}
+
+type SlowApplication struct {
+	slept bool
+}
+
+func (app *SlowApplication) Sleep(dur time.Duration) {
+	time.Sleep(dur)
+	app.slept = true
+}
+
+type CopyApplication struct {
+	src, dst string
+}
+
+func (app *CopyApplication) Copy(src, dst string) {
+	app.src, app.dst = src, dst
+}
+
+func (app *CopyApplication) GetArgumentNames(cmd string) []string {
+	if cmd == "copy" {
+		return []string{"src", "dst"}
+	}
+	return nil
+}
+
+type contextKey struct{}
+
+type ContextApplication struct {
+	hooked   interface{}
+	received interface{}
+	canceled chan struct{}
+}
+
+func (app *ContextApplication) PostFlagParseContext(ctx context.Context) error {
+	app.hooked = ctx.Value(contextKey{})
+	return nil
+}
+
+func (app *ContextApplication) Run(ctx context.Context, name string) {
+	app.received = ctx.Value(contextKey{})
+}
+
+func (app *ContextApplication) Wait(ctx context.Context) error {
+	<-ctx.Done()
+	close(app.canceled)
+	return ctx.Err()
+}
+
+type AllowlistApplication struct {
+	Helped bool
+}
+
+func (app *AllowlistApplication) Help() { app.Helped = true }
+
+func (app *AllowlistApplication) Helper() {}
+
+func (app *AllowlistApplication) GetAllowedCommands() []string { return []string{"help"} }
+
+type RenamedApplication struct {
+	Listed bool
+}
+
+func (app *RenamedApplication) ListAll() { app.Listed = true }
+
+func (app *RenamedApplication) CommandNames() map[string]string {
+	return map[string]string{"ListAll": "ls"}
+}
+
+type DeprecatingApplication struct {
+	Sub *SubSubApplication `commander:"subcommand=sub,Use sub commands"`
+	Old *SubSubApplication `commander:"subcommand=old,Use old commands,deprecated"`
+	_   struct{}           `commander:"subcommand=legacy,renamed=sub"`
+}
+
+type ScopingApplication struct {
+	Copy struct {
+		Force bool `commander:"flag=force,Overwrite the destination"`
+	} `commander:"flagstruct=manage copy"`
+
+	Manage *CopyApplication `commander:"subcommand=manage"`
+}
+
+type TLSFlagStruct struct {
+	Cert string `commander:"flag=cert"`
+	Key  string `commander:"flag=key"`
+}
+
+func (flags *TLSFlagStruct) Validate() error {
+	if (flags.Cert == "") != (flags.Key == "") {
+		return fmt.Errorf("either --cert and --key or neither")
+	}
+	return nil
+}
+
+type ValidatedApplication struct {
+	Port int           `commander:"flag=port"`
+	TLS  TLSFlagStruct `commander:"flagstruct"`
+
+	served bool
+}
+
+func (app *ValidatedApplication) Validate() error {
+	if app.Port <= 0 {
+		return fmt.Errorf("--port must be positive")
+	}
+	return nil
+}
+
+func (app *ValidatedApplication) Serve() { app.served = true }
+
+type StreamApplication struct{}
+
+func (app *StreamApplication) Upper(in io.Reader, out io.Writer, prefix string) error {
+	content, err := ioutil.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprint(out, prefix+strings.ToUpper(string(content)))
+	return err
+}
+
+type TopicsApplication struct{}
+
+func (app *TopicsApplication) Run() {}
+
+func (app *TopicsApplication) GetHelpTopics() map[string]string {
+	return map[string]string{
+		"environment":   "Environment variables read by the application.\n\nHOME is where the config lives.\n",
+		"config-format": "The configuration is a JSON object.",
+	}
+}
+
+type PayloadApplication struct {
+	Meta json.RawMessage `commander:"flag=meta"`
+
+	sent json.RawMessage
+}
+
+func (app *PayloadApplication) Send(payload json.RawMessage) { app.sent = payload }
+
+type PathApplication struct {
+	path     []string
+	hookPath []string
+
+	Left  *PathApplication `commander:"subcommand=left"`
+	Right *PathApplication `commander:"subcommand=right"`
+}
+
+func (app *PathApplication) SetCommandPath(path []string) { app.path = path }
+
+func (app *PathApplication) PostFlagParse() error {
+	app.hookPath = app.path
+	return nil
+}
+
+func (app *PathApplication) Run() {}
@@ -0,0 +1,237 @@
+package commander
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ValidationError lists the problems that Validate found with the tags of an application.
+type ValidationError struct {
+	Problems []string
+}
+
+func (err ValidationError) Error() string {
+	return "invalid commander tags:\n  " + strings.Join(err.Problems, "\n  ")
+}
+
+// Validate checks the commander tags of the application and of all the flagstructs, flag slices and
+// subcommands reachable from it. It reports unknown directives, flags and subcommands without
+// names, flagstructs bound to commands that do not exist and tags on unexported fields, all of
+// which RunCLI would otherwise silently ignore, along with the subcommands and flagstructs that
+// refer back to one of the structs they are reached from. The problems are returned as a
+// ValidationError.
+func Validate(app interface{}) error {
+	validator := &tagValidator{visited: map[reflect.Type]bool{}, ancestors: map[structPointer]string{}}
+	validator.validate(reflect.ValueOf(app), reflect.TypeOf(app), "")
+	if len(validator.problems) > 0 {
+		return ValidationError{Problems: validator.problems}
+	}
+	return nil
+}
+
+type tagValidator struct {
+	visited  map[reflect.Type]bool
+	problems []string
+
+	// ancestors holds the paths of the structs being checked, by the pointers that lead to them.
+	ancestors map[structPointer]string
+}
+
+// structPointer identifies a struct by its address and type, since a struct and its first field
+// share their address.
+type structPointer struct {
+	address uintptr
+	t       reflect.Type
+}
+
+func (validator *tagValidator) addProblem(path string, format string, args ...interface{}) {
+	validator.problems = append(validator.problems, path+": "+fmt.Sprintf(format, args...))
+}
+
+// validate checks the struct that the value points to. The value can be invalid or nil, in which
+// case the struct is checked from its type alone.
+func (validator *tagValidator) validate(v reflect.Value, t reflect.Type, path string) {
+	var pointer structPointer
+	for t != nil && (t.Kind() == reflect.Ptr || t.Kind() == reflect.Interface) {
+		if !v.IsValid() || v.IsNil() {
+			v = reflect.Value{}
+			if t.Kind() == reflect.Interface {
+				return
+			}
+			t = t.Elem()
+			continue
+		}
+		if v.Kind() == reflect.Ptr {
+			pointer = structPointer{address: v.Pointer(), t: v.Type()}
+		}
+		v = v.Elem()
+		t = v.Type()
+	}
+	if ancestor, found := validator.ancestors[pointer]; found {
+		validator.addProblem(path, "cycle back to %v", ancestor)
+		return
+	}
+	if t == nil || t.Kind() != reflect.Struct || validator.visited[t] {
+		return
+	}
+	validator.visited[t] = true
+	if path == "" {
+		path = t.Name()
+	}
+	if pointer.address != 0 {
+		validator.ancestors[pointer] = path
+		defer delete(validator.ancestors, pointer)
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		alias, ok := field.Tag.Lookup(FieldTag)
+		if !ok {
+			continue
+		}
+		fieldpath := path + "." + field.Name
+		var fieldval reflect.Value
+		if v.IsValid() {
+			fieldval = v.Field(i)
+		}
+
+		if field.PkgPath != "" && field.Name != "_" {
+			validator.addProblem(fieldpath, "tag on unexported field")
+			continue
+		}
+
+		if options, isFlag, err := parseFlagTag(alias); err != nil {
+			validator.addProblem(fieldpath, "%v", err)
+			continue
+		} else if isFlag && options.name == "" {
+			validator.addProblem(fieldpath, "flag directive without a flag name")
+			continue
+		} else if isFlag {
+			continue
+		}
+
+		split := strings.SplitN(alias, "=", 2)
+		switch split[0] {
+		case FlagDirective:
+			validator.addProblem(fieldpath, "flag directive without a flag name")
+		case SubcommandDirective:
+			if len(split) != 2 || strings.SplitN(split[1], ",", 2)[0] == "" {
+				validator.addProblem(fieldpath, "subcommand directive without a subcommand name")
+				continue
+			} else if directive, err := parseSubcommandDirective(split[1]); err != nil {
+				validator.addProblem(fieldpath, "%v", err)
+			} else if directive.renamed != "" && !typeHasSubcommand(t, directive.renamed) {
+				validator.addProblem(fieldpath, "subcommand renamed to %v, which does not exist", directive.renamed)
+			}
+			validator.validate(fieldval, field.Type, fieldpath)
+		case FlagStructDirective:
+			if len(split) == 2 {
+				directive, _ := parseSubcommandDirective(split[1])
+				if !typeHasCommand(t, directive.cmd) {
+					validator.addProblem(fieldpath, "flagstruct bound to command %v, which does not exist", directive.cmd)
+				}
+			}
+			validator.validate(fieldval, field.Type, fieldpath)
+		case FlagSliceDirective:
+			if field.Type.Kind() != reflect.Slice {
+				validator.addProblem(fieldpath, "flagslice directive on a field of type %v", field.Type)
+				continue
+			}
+			for j := 0; fieldval.IsValid() && j < fieldval.Len(); j++ {
+				validator.validate(fieldval.Index(j), fieldval.Index(j).Type(), fmt.Sprintf("%v[%d]", fieldpath, j))
+			}
+		case SubcommandsDirective:
+			if field.Type.Kind() != reflect.Map || field.Type.Key().Kind() != reflect.String {
+				validator.addProblem(fieldpath, "subcommands directive on a field of type %v", field.Type)
+				continue
+			}
+			for _, key := range sortedMapKeys(fieldval) {
+				validator.validate(fieldval.MapIndex(key), field.Type.Elem(), fmt.Sprintf("%v[%q]", fieldpath, key.String()))
+			}
+		case InjectDirective:
+			if len(split) != 2 || split[1] == "" {
+				validator.addProblem(fieldpath, "inject directive without a name")
+			}
+		case EnvDirective:
+			if len(split) != 2 || strings.SplitN(split[1], ",", 2)[0] == "" {
+				validator.addProblem(fieldpath, "env directive without a variable name")
+			}
+		case LockDirective:
+			if len(split) != 2 || split[1] == "" {
+				validator.addProblem(fieldpath, "lock directive without a path")
+			}
+		case PluginsDirective, ArgDirective, ColumnDirective:
+		default:
+			validator.addProblem(fieldpath, "unknown directive %q", split[0])
+		}
+	}
+}
+
+// typeHasCommand returns true if a pointer to the struct type given has a method for the command.
+// The command can be a space-separated path, whose last element is a command of the subcommands
+// named by the others.
+func typeHasCommand(t reflect.Type, cmd string) bool {
+	path := strings.Fields(cmd)
+	if len(path) == 0 {
+		return false
+	}
+	for _, sub := range path[:len(path)-1] {
+		if t = subcommandType(t, sub); t == nil {
+			return false
+		}
+	}
+
+	ptr := reflect.PtrTo(t)
+	for i := 0; i < ptr.NumMethod(); i++ {
+		if strings.ToLower(ptr.Method(i).Name) == normalizeCommand(path[len(path)-1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// subcommandType returns the struct type of the subcommand named, or nil if there is no such
+// subcommand.
+func subcommandType(t reflect.Type, cmd string) reflect.Type {
+	for i := 0; i < t.NumField(); i++ {
+		split := strings.SplitN(t.Field(i).Tag.Get(FieldTag), "=", 2)
+		if len(split) != 2 || split[0] != SubcommandDirective {
+			continue
+		} else if directive, _ := parseSubcommandDirective(split[1]); directive.cmd != cmd {
+			continue
+		}
+		sub := t.Field(i).Type
+		for sub.Kind() == reflect.Ptr {
+			sub = sub.Elem()
+		}
+		if sub.Kind() == reflect.Struct {
+			return sub
+		}
+	}
+	return nil
+}
+
+// typeHasSubcommand returns true if a field of the struct type given is the subcommand named.
+func typeHasSubcommand(t reflect.Type, cmd string) bool {
+	for i := 0; i < t.NumField(); i++ {
+		split := strings.SplitN(t.Field(i).Tag.Get(FieldTag), "=", 2)
+		if len(split) != 2 || split[0] != SubcommandDirective {
+			continue
+		} else if directive, _ := parseSubcommandDirective(split[1]); directive.cmd == cmd && directive.renamed == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedMapKeys returns the keys of the map sorted, or nothing if the map is not valid.
+func sortedMapKeys(m reflect.Value) []reflect.Value {
+	if !m.IsValid() {
+		return nil
+	}
+	keys := m.MapKeys()
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+	return keys
+}
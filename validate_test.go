@@ -0,0 +1,99 @@
+package commander_test
+
+import (
+	"testing"
+
+	"github.com/apourchet/commander"
+	"github.com/stretchr/testify/require"
+)
+
+type InvalidApplication struct {
+	Unknown  string             `commander:"flags=unknown"`
+	NoName   string             `commander:"flag=,Some usage"`
+	Bare     string             `commander:"flag"`
+	Option   string             `commander:"flag,name=opt,shorthand=o"`
+	hidden   string             `commander:"flag=hidden"`
+	NoCmd    struct{}           `commander:"flagstruct=nocmd"`
+	Timeout  *SubSubApplication `commander:"subcommand=sub,timeout=never"`
+	Nested   InvalidNested      `commander:"flagstruct"`
+	NotSlice int                `commander:"flagslice"`
+	Slice    []interface{}      `commander:"flagslice"`
+	Renamed  struct{}           `commander:"subcommand=old,renamed=new"`
+	NoEnv    string             `commander:"env=,Some usage"`
+}
+
+type InvalidNested struct {
+	Bad int `commander:"subcommand"`
+}
+
+type CyclicApplication struct {
+	Parent *CyclicApplication `commander:"subcommand=parent"`
+	Other  interface{}        `commander:"subcommand=other"`
+	Flags  *CyclicFlags       `commander:"flagstruct"`
+}
+
+type CyclicFlags struct {
+	Level int          `commander:"flag=level"`
+	Back  *CyclicFlags `commander:"flagstruct"`
+}
+
+func (app *CyclicApplication) Run() {}
+
+func TestValidate(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		apps := []interface{}{
+			&Application{},
+			&Application2{},
+			&Application3{},
+			&FlagTesterNested{},
+			&FlagTesterSliced{Slice: []interface{}{&IntFlagStruct{}}},
+			&PluginHost{},
+			&TypedApplication{},
+		}
+		for _, app := range apps {
+			require.NoError(t, commander.Validate(app))
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		app := &InvalidApplication{Slice: []interface{}{&InvalidNested{}}}
+		err := commander.Validate(app)
+		require.Error(t, err)
+		require.Equal(t, []string{
+			`InvalidApplication.Unknown: unknown directive "flags"`,
+			`InvalidApplication.NoName: flag directive without a flag name`,
+			`InvalidApplication.Bare: flag directive without a flag name`,
+			`InvalidApplication.Option: unknown flag option "shorthand=o"`,
+			`InvalidApplication.hidden: tag on unexported field`,
+			`InvalidApplication.NoCmd: flagstruct bound to command nocmd, which does not exist`,
+			`InvalidApplication.Timeout: malformed timeout on subcommand sub: time: invalid duration "never"`,
+			`InvalidApplication.Nested.Bad: subcommand directive without a subcommand name`,
+			`InvalidApplication.NotSlice: flagslice directive on a field of type int`,
+			`InvalidApplication.Renamed: subcommand renamed to new, which does not exist`,
+			`InvalidApplication.NoEnv: env directive without a variable name`,
+		}, err.(commander.ValidationError).Problems)
+	})
+
+	t.Run("cycles", func(t *testing.T) {
+		app := &CyclicApplication{Flags: &CyclicFlags{}}
+		app.Parent, app.Other, app.Flags.Back = app, app, app.Flags
+		err := commander.Validate(app)
+		require.Error(t, err)
+		require.Equal(t, []string{
+			`CyclicApplication.Parent: cycle back to CyclicApplication`,
+			`CyclicApplication.Other: cycle back to CyclicApplication`,
+			`CyclicApplication.Flags.Back: cycle back to CyclicApplication.Flags`,
+		}, err.(commander.ValidationError).Problems)
+
+		_, err = commander.New().GetFlagSet(app, "CLI")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Cycle of flagstructs: CyclicApplication.Flags.Back refers back to CyclicApplication.Flags")
+	})
+
+	t.Run("strict_run", func(t *testing.T) {
+		cmd := commander.New()
+		cmd.StrictTags = true
+		require.Error(t, cmd.RunCLI(&InvalidApplication{}, []string{"opthree"}))
+		require.Equal(t, errTest, cmd.RunCLI(&Application{}, []string{"opthree"}))
+	})
+}
@@ -0,0 +1,50 @@
+package commander
+
+import (
+	"runtime/debug"
+	"strings"
+)
+
+// BuildVersion returns the version of the running binary as recorded by the Go toolchain: the
+// version of its main module, followed by the VCS revision and whether the working tree was
+// modified, like "v1.2.0 (rev 1a2b3c4d5e6f, dirty)". It returns "(devel)" when the binary does
+// not carry that information.
+//
+// Applications that do not supply a version string of their own can use it to report one.
+func BuildVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "(devel)"
+	}
+	return buildVersion(info)
+}
+
+func buildVersion(info *debug.BuildInfo) string {
+	version := info.Main.Version
+	if version == "" {
+		version = "(devel)"
+	}
+
+	var revision string
+	var modified bool
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	if revision == "" {
+		return version
+	}
+
+	if len(revision) > 12 {
+		revision = revision[:12]
+	}
+	details := []string{"rev " + revision}
+	if modified {
+		details = append(details, "dirty")
+	}
+	return version + " (" + strings.Join(details, ", ") + ")"
+}
@@ -0,0 +1,19 @@
+package commander_test
+
+import (
+	"runtime/debug"
+	"testing"
+
+	"github.com/apourchet/commander"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildVersion(t *testing.T) {
+	info, ok := debug.ReadBuildInfo()
+	require.True(t, ok)
+	version := commander.BuildVersion()
+	require.NotEmpty(t, version)
+	if info.Main.Version != "" {
+		require.Contains(t, version, info.Main.Version)
+	}
+}
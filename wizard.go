@@ -0,0 +1,195 @@
+package commander
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/apourchet/commander/utils"
+	"github.com/pkg/errors"
+)
+
+// RunWizard walks the user through an invocation of the application: it prompts on the Stdout of
+// the Commander for a subcommand or command to run at each level, then for the value of every flag
+// and positional argument, reading the answers from its Stdin. Empty answers keep the defaults of
+// the flags, and answers that do not parse to the type of the flag or argument are asked again.
+// The equivalent command line is printed before the invocation is run with RunCLI.
+func (commander Commander) RunWizard(app interface{}) error {
+	wizard := &wizard{in: bufio.NewReader(commander.Stdin), out: commander.Stdout}
+	args, err := wizard.walk(commander, app)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(wizard.out, "\n$ %v %v\n", getCLIName(app), ShellJoin(args))
+	return commander.RunCLI(app, args)
+}
+
+type wizard struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// walk prompts for the arguments of an invocation of the application, descending into the
+// subcommands that the user chooses until they choose a command.
+func (wizard *wizard) walk(commander Commander, app interface{}) ([]string, error) {
+	args := []string{}
+	appname := getCLIName(app)
+	for {
+		flagset, err := commander.GetFlagSet(app, appname)
+		if err != nil {
+			return nil, err
+		}
+		flags, err := wizard.askFlags(flagset)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, flags...)
+
+		cmd, err := wizard.choose(app, appname)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, cmd)
+		if subapp, _, err := subCommand(app, cmd); err != nil {
+			return nil, err
+		} else if subapp != nil {
+			app, appname = subapp, appname+" "+cmd
+			continue
+		}
+
+		cmdset, err := commander.GetFlagSetWithCommand(app, appname, cmd)
+		if err != nil {
+			return nil, err
+		}
+		if flags, err = wizard.askFlags(cmdset); err != nil {
+			return nil, err
+		}
+		args = append(args, flags...)
+
+		for _, arg := range commandArguments(app, cmd) {
+			values, err := wizard.askArgument(arg)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, values...)
+		}
+		return args, nil
+	}
+}
+
+// choose lists the commands and subcommands of the application and returns the one that the user
+// picks, by number or by name.
+func (wizard *wizard) choose(app interface{}, appname string) (string, error) {
+	choices, descriptions := commandNames(app), map[string]string{}
+	if st, valid := utils.DerefType(app); valid {
+		for i := 0; i < st.NumField(); i++ {
+			split := strings.SplitN(st.Field(i).Tag.Get(FieldTag), "=", 2)
+			if len(split) != 2 || split[0] != SubcommandDirective {
+				continue
+			}
+			if directive, _ := parseSubcommandDirective(split[1]); directive.renamed == "" {
+				choices = append(choices, directive.cmd)
+				descriptions[directive.cmd] = directive.usageDescription()
+			}
+		}
+	}
+	choices = append(choices, dynamicSubcommandNames(app)...)
+	if len(choices) == 0 {
+		return "", fmt.Errorf("%v has no commands to run", appname)
+	}
+
+	fmt.Fprintf(wizard.out, "%v:\n", appname)
+	for i, choice := range choices {
+		desc := descriptions[choice]
+		if provider, ok := app.(CommandDescriptionProvider); ok {
+			if newdesc := provider.GetCommandDescription(choice); newdesc != "" {
+				desc = newdesc
+			}
+		}
+		if desc != "" {
+			fmt.Fprintf(wizard.out, "  %d) %v  |  %v\n", i+1, choice, desc)
+		} else {
+			fmt.Fprintf(wizard.out, "  %d) %v\n", i+1, choice)
+		}
+	}
+
+	for {
+		answer, err := wizard.ask("Command")
+		if err != nil {
+			return "", err
+		}
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(choices) {
+			return choices[n-1], nil
+		} else if containsString(choices, answer) {
+			return answer, nil
+		}
+		fmt.Fprintf(wizard.out, "Unknown command %q, choose one of 1-%d\n", answer, len(choices))
+	}
+}
+
+// askFlags prompts for the value of each flag of the set and returns the flag arguments of the
+// values given. Flags that are left empty are not part of the arguments.
+func (wizard *wizard) askFlags(flagset *FlagSet) ([]string, error) {
+	args := []string{}
+	for _, info := range flagset.Targets() {
+		prompt := fmt.Sprintf("-%v (%v)", info.Name, argumentTypeName(info.Type))
+		if info.Usage != "" {
+			prompt = fmt.Sprintf("-%v (%v): %v", info.Name, argumentTypeName(info.Type), info.Usage)
+		}
+		if info.Value != "" {
+			prompt += fmt.Sprintf(" [%v]", info.Value)
+		}
+		for {
+			answer, err := wizard.ask(prompt)
+			if err != nil {
+				return nil, err
+			} else if answer == "" {
+				break
+			} else if _, err := utils.ParseString(info.Type, answer); err != nil {
+				fmt.Fprintf(wizard.out, "Invalid value for -%v: %v\n", info.Name, err)
+				continue
+			}
+			args = append(args, fmt.Sprintf("-%v=%v", info.Name, answer))
+			break
+		}
+	}
+	return args, nil
+}
+
+// askArgument prompts for the value of a positional argument. The trailing arguments of a command
+// are given on a single line, separated by spaces, and can be left empty.
+func (wizard *wizard) askArgument(arg commandArgument) ([]string, error) {
+	prompt := "<" + arg.name + ">"
+	if arg.placeholder != "" {
+		prompt = arg.placeholder
+	}
+	for {
+		answer, err := wizard.ask(prompt)
+		if err != nil {
+			return nil, err
+		} else if arg.trailing {
+			return strings.Fields(answer), nil
+		} else if answer == "" {
+			fmt.Fprintf(wizard.out, "%v is required\n", prompt)
+			continue
+		} else if _, err := utils.ParseString(arg.t, answer); err != nil {
+			fmt.Fprintf(wizard.out, "Invalid value for %v: %v\n", prompt, err)
+			continue
+		}
+		return []string{answer}, nil
+	}
+}
+
+// ask prints the prompt and returns the next line of the input, trimmed.
+func (wizard *wizard) ask(prompt string) (string, error) {
+	fmt.Fprintf(wizard.out, "%v: ", prompt)
+	line, err := wizard.in.ReadString('\n')
+	if err == io.EOF && line == "" {
+		return "", errors.New("wizard input ended before the invocation was complete")
+	} else if err != nil && err != io.EOF {
+		return "", errors.Wrap(err, "failed to read wizard input")
+	}
+	return strings.TrimSpace(line), nil
+}
@@ -0,0 +1,46 @@
+package commander_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/apourchet/commander"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWizard(t *testing.T) {
+	var out bytes.Buffer
+	cmd := commander.New()
+	cmd.Stdout = &out
+	cmd.Stdin = strings.NewReader("ten\n10\nsubapp\n\nopfour\ntest=testing\n")
+	app := &Application{SubApp: &SubApplication{}}
+	require.NoError(t, cmd.RunWizard(app))
+	require.Equal(t, 10, app.IntFlag)
+	require.Equal(t, 1, app.SubApp.count)
+	require.Contains(t, out.String(), "Invalid value for -intflag")
+	require.Contains(t, out.String(), "subapp  |  Use subapp commands")
+	require.Contains(t, out.String(), "$ myapp -intflag=10 subapp opfour test=testing\n")
+}
+
+func TestWizardArguments(t *testing.T) {
+	var out bytes.Buffer
+	cmd := commander.New()
+	cmd.Stdout = &out
+	cmd.Stdin = strings.NewReader("1\n\na\nb c\n")
+	app := &CopyApplication{}
+	require.NoError(t, cmd.RunWizard(app))
+	require.Equal(t, "a", app.src)
+	require.Equal(t, "b c", app.dst)
+	require.Contains(t, out.String(), "<src>: <dst>: ")
+	require.Contains(t, out.String(), "<src> is required")
+}
+
+func TestWizardEndOfInput(t *testing.T) {
+	cmd := commander.New()
+	cmd.Stdout = &bytes.Buffer{}
+	cmd.Stdin = strings.NewReader("10\n")
+	app := &Application{}
+	require.Error(t, cmd.RunWizard(app))
+	require.Equal(t, 0, app.IntFlag)
+}